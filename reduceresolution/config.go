@@ -4,15 +4,473 @@
 
 package reduceresolution
 
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
 type Config struct {
-	MetricStatistics map[string][]string `mapstructure:"gauge-aggregations"`
+	MetricStatistics map[string][]string      `mapstructure:"gauge-aggregations"`
+	MetricsOptions   map[string]MetricOptions `mapstructure:"metric-options"`
+	// Statistics of the gauges that are not listed in gauge-aggregations, the first matching rule is used
+	GaugeAggregationRules []GaugeAggregationRule `mapstructure:"gauge-aggregation-rules"`
+	// Statistics emitted for counters and histograms, which are emitted as they are when not listed
+	SumStatistics       map[string][]string `mapstructure:"sum-aggregations"`
+	HistogramStatistics map[string][]string `mapstructure:"histogram-aggregations"`
+	// Statistics of the gauges that are neither listed nor matched by a rule. An empty list drops
+	// those gauges, and passthrough forwards them unchanged
+	DefaultGaugeAggregations []string `mapstructure:"default-gauge-aggregations"`
+	// When set, every resource is also merged into a cohort resource that only has these
+	// attributes, which is emitted in addition to the resources that were received
+	RollupResourceAttributes []string `mapstructure:"rollup-resource-attributes"`
+	// Interval enables the stateful mode, where metrics are aggregated across
+	// calls and flushed to the next consumer once per interval. When it is
+	// zero, every received batch is reduced on its own.
+	Interval time.Duration `mapstructure:"interval"`
+	// Relative accuracy of the quantiles estimated for gauges, like p95
+	RelativeAccuracy float64 `mapstructure:"relative-accuracy"`
+	// Whether the statistic is appended to the metric name, or carried as a datapoint attribute
+	StatisticNaming string `mapstructure:"statistic-naming"`
+	// Name of the datapoint attribute that carries the statistic
+	StatisticAttribute string `mapstructure:"statistic-attribute"`
+	// Name of the metric emitted for each statistic, where {name}, {stat} and {unit} are replaced
+	NameTemplate string `mapstructure:"name-template"`
+	// What is done when a cumulative counter or histogram is reset within the window
+	CumulativeResets string `mapstructure:"cumulative-resets"`
+	// Temporality of the emitted counters and histograms, they keep the received one when it is empty
+	OutputTemporality string `mapstructure:"output-temporality"`
+	// What is done with histogram datapoints whose bucket bounds differ from the series they are aggregated into
+	HistogramBoundsMismatch string `mapstructure:"histogram-bounds-mismatch"`
+	// Which value of each quantile of a summary is emitted for the window
+	SummaryQuantiles string `mapstructure:"summary-quantiles"`
+	// Number of windows without any datapoint after which the state kept for a series is dropped,
+	// zero keeps it forever
+	SeriesExpiry int `mapstructure:"series-expiry"`
+}
+
+// Possible values of output-temporality
+const (
+	OutputTemporalityDelta      = "delta"
+	OutputTemporalityCumulative = "cumulative"
+)
+
+// Possible values of cumulative-resets
+const (
+	// The total before the reset is added to the values after it, so no increase is lost
+	CumulativeResetsAccumulate = "accumulate"
+	// Only the values after the reset are kept, with the reset as the start of the series
+	CumulativeResetsRestart = "restart"
+)
+
+// Possible values of histogram-bounds-mismatch
+const (
+	// The datapoint is dropped
+	HistogramBoundsMismatchDrop = "drop"
+	// Both are re-bucketed onto the bounds they have in common, which only merges buckets
+	HistogramBoundsMismatchCommon = "common"
+	// Both are re-bucketed onto all the bounds of either, splitting the buckets proportionally
+	HistogramBoundsMismatchUnion = "union"
+	// Both are re-bucketed onto the explicit-bounds of the metric
+	HistogramBoundsMismatchConfigured = "configured"
+)
+
+// Possible values of summary-quantiles
+const (
+	// The quantiles of the latest datapoint
+	SummaryQuantilesLatest = "latest"
+	// The highest value of each quantile within the window
+	SummaryQuantilesMax = "max"
+)
+
+// Possible values of statistic-naming
+const (
+	StatisticNamingSuffix    = "suffix"
+	StatisticNamingAttribute = "attribute"
+)
+
+// Used to drop the state of the series that stopped when no other expiry is configured
+const DefaultSeriesExpiry = 10
+
+// Used to carry the statistic when no other attribute is configured
+const DefaultStatisticAttribute = "stat"
+
+// Used to name the metric of each statistic when no other template is configured
+const (
+	DefaultGaugeNameTemplate = "{name}_gauge_{stat}"
+	DefaultNameTemplate      = "{name}_{stat}"
+)
+
+// Used for the gauges that are neither listed nor matched by a rule, when no other default is configured
+var DefaultGaugeStatistics = []Statistic{StatisticAbsMax, StatisticAbsMin}
+
+// Used for the quantiles when no relative accuracy is configured
+const DefaultRelativeAccuracy = 0.01
+
+// GaugeAggregationRule selects the statistics of every gauge whose name matches either a glob or a regex
+type GaugeAggregationRule struct {
+	Glob       string   `mapstructure:"glob"`
+	Regex      string   `mapstructure:"regex"`
+	Statistics []string `mapstructure:"statistics"`
+}
+
+// Pattern returns the glob or the regex of the rule, as it was configured
+func (rule GaugeAggregationRule) Pattern() string {
+	if rule.Glob != "" {
+		return rule.Glob
+	}
+	return rule.Regex
+}
+
+// ProcessedGaugeAggregationRule is a GaugeAggregationRule ready to be matched, ignoring case
+type ProcessedGaugeAggregationRule struct {
+	GaugeAggregationRule
+	glob       string
+	regex      *regexp.Regexp
+	statistics []Statistic
+}
+
+func ProcessGaugeAggregationRule(rule GaugeAggregationRule) (ProcessedGaugeAggregationRule, error) {
+	processedRule := ProcessedGaugeAggregationRule{GaugeAggregationRule: rule}
+	if (rule.Glob == "") == (rule.Regex == "") {
+		return processedRule, errors.New("exactly one of glob and regex must be set")
+	}
+	statistics, err := GaugeStatisticSet.ParseStatistics(rule.Statistics)
+	if err != nil {
+		return processedRule, fmt.Errorf("statistics::%w", err)
+	}
+	processedRule.statistics = statistics
+	if rule.Glob != "" {
+		processedRule.glob = strings.ToLower(rule.Glob)
+		if _, err := path.Match(processedRule.glob, ""); err != nil {
+			return processedRule, fmt.Errorf("invalid glob %q: %w", rule.Glob, err)
+		}
+		return processedRule, nil
+	}
+	regex, err := regexp.Compile("(?i)^(?:" + rule.Regex + ")$")
+	if err != nil {
+		return processedRule, fmt.Errorf("invalid regex %q: %w", rule.Regex, err)
+	}
+	processedRule.regex = regex
+	return processedRule, nil
+}
+
+// Matches tells if the whole name of the metric matches the rule
+func (rule ProcessedGaugeAggregationRule) Matches(name string) bool {
+	if rule.regex != nil {
+		return rule.regex.MatchString(name)
+	}
+	matched, _ := path.Match(rule.glob, strings.ToLower(name))
+	return matched
 }
 
+// MetricOptions holds the settings that apply to a single metric, whatever its type
+type MetricOptions struct {
+	// Only these datapoint attributes are kept, and the series that become equal are merged
+	KeepAttributes []string `mapstructure:"keep-attributes"`
+	// These datapoint attributes are removed, and the series that become equal are merged
+	DropAttributes []string `mapstructure:"drop-attributes"`
+	// How a gauge is emitted, either as one gauge for each statistic, or as a single histogram or summary
+	GaugeOutput string `mapstructure:"gauge-output"`
+	// Bucket bounds of the histogram a gauge is emitted as, or that histograms with different bounds are merged into
+	ExplicitBounds []float64 `mapstructure:"explicit-bounds"`
+	// Maximum number of buckets for each sign of the exponential histogram a gauge is emitted as
+	MaxBuckets int `mapstructure:"max-buckets"`
+	// Overrides the global name-template for this metric
+	NameTemplate string `mapstructure:"name-template"`
+	// Full name of the metric emitted for a statistic, which takes precedence over any template
+	StatisticNames map[string]string `mapstructure:"statistic-names"`
+	// Overrides the global cumulative-resets for this metric
+	CumulativeResets string `mapstructure:"cumulative-resets"`
+	// How a counter is emitted, either as the sum of the window or as its rate per second
+	CounterOutput string `mapstructure:"counter-output"`
+	// Overrides the global histogram-bounds-mismatch for this metric
+	HistogramBoundsMismatch string `mapstructure:"histogram-bounds-mismatch"`
+	// Overrides the global summary-quantiles for this metric
+	SummaryQuantiles string `mapstructure:"summary-quantiles"`
+	// Coarser bucket bounds the histogram is emitted with
+	CoarseBounds []float64 `mapstructure:"coarse-bounds"`
+	// Number of adjacent buckets of the histogram that are merged into one when it is emitted
+	MergeBuckets int `mapstructure:"merge-buckets"`
+	// Whether the histogram is emitted next to its statistics, or only its statistics
+	HistogramOutput string `mapstructure:"histogram-output"`
+}
+
+// Possible values of histogram-output
+const (
+	HistogramOutputHistogram  = "histogram"
+	HistogramOutputStatistics = "statistics"
+)
+
+// Possible values of counter-output
+const (
+	CounterOutputSum  = "sum"
+	CounterOutputRate = "rate"
+)
+
+// Possible values of gauge-output
+const (
+	GaugeOutputStatistics = "statistics"
+	GaugeOutputHistogram  = "histogram"
+
+	GaugeOutputExponentialHistogram = "exponential-histogram"
+	GaugeOutputSummary              = "summary"
+)
+
 type ProcessedConfig struct {
-	MetricsStatistics map[string][]string
+	MetricsStatistics map[string][]Statistic
+	MetricsOptions    map[string]MetricOptions
+	Interval          time.Duration
+	RelativeAccuracy  float64
+
+	SumStatistics       map[string][]Statistic
+	HistogramStatistics map[string][]Statistic
+
+	GaugeAggregationRules []ProcessedGaugeAggregationRule
+	// When nil the DefaultGaugeStatistics are used, when empty the gauges are dropped
+	DefaultGaugeStatistics []Statistic
+
+	StatisticNaming    string
+	StatisticAttribute string
+	NameTemplate       string
+	CumulativeResets   string
+	OutputTemporality  string
+
+	HistogramBoundsMismatch string
+	SummaryQuantiles        string
+	SeriesExpiry            int
+
+	RollupResourceAttributes []string
 }
 
 // Validate checks if the receiver configuration is valid
 func (cfg *Config) Validate() error {
+	if cfg.Interval < 0 {
+		return errors.New("interval must not be negative")
+	}
+	if cfg.RelativeAccuracy <= 0 || cfg.RelativeAccuracy >= 1 {
+		return errors.New("relative-accuracy must be between 0 and 1")
+	}
+	switch cfg.StatisticNaming {
+	case StatisticNamingSuffix:
+	case StatisticNamingAttribute:
+		if cfg.StatisticAttribute == "" {
+			return errors.New("statistic-attribute must not be empty")
+		}
+	default:
+		return fmt.Errorf("unknown statistic-naming %q", cfg.StatisticNaming)
+	}
+	if err := checkCaseInsensitiveKeys(cfg.MetricsOptions); err != nil {
+		return fmt.Errorf("metric-options: %w", err)
+	}
+	for _, block := range []struct {
+		name       string
+		statistics map[string][]string
+		set        StatisticSet
+	}{
+		{"gauge-aggregations", cfg.MetricStatistics, GaugeStatisticSet},
+		{"sum-aggregations", cfg.SumStatistics, SumStatisticSet},
+		{"histogram-aggregations", cfg.HistogramStatistics, HistogramStatisticSet},
+	} {
+		if err := checkCaseInsensitiveKeys(block.statistics); err != nil {
+			return fmt.Errorf("%s: %w", block.name, err)
+		}
+		for metricName, statistics := range block.statistics {
+			if _, err := block.set.ParseStatistics(statistics); err != nil {
+				return fmt.Errorf("%s::%s::%w", block.name, metricName, err)
+			}
+		}
+	}
+	if _, err := GaugeStatisticSet.ParseStatistics(cfg.DefaultGaugeAggregations); err != nil {
+		return fmt.Errorf("default-gauge-aggregations::%w", err)
+	}
+	for i, rule := range cfg.GaugeAggregationRules {
+		if _, err := ProcessGaugeAggregationRule(rule); err != nil {
+			return fmt.Errorf("gauge-aggregation-rules::%d: %w", i, err)
+		}
+	}
+	switch cfg.CumulativeResets {
+	case CumulativeResetsAccumulate, CumulativeResetsRestart:
+	default:
+		return fmt.Errorf("unknown cumulative-resets %q", cfg.CumulativeResets)
+	}
+	switch cfg.OutputTemporality {
+	case "", OutputTemporalityDelta, OutputTemporalityCumulative:
+	default:
+		return fmt.Errorf("unknown output-temporality %q", cfg.OutputTemporality)
+	}
+	switch cfg.HistogramBoundsMismatch {
+	case HistogramBoundsMismatchDrop, HistogramBoundsMismatchCommon, HistogramBoundsMismatchUnion:
+	case HistogramBoundsMismatchConfigured:
+		return errors.New("histogram-bounds-mismatch configured can only be set for a metric with explicit-bounds")
+	default:
+		return fmt.Errorf("unknown histogram-bounds-mismatch %q", cfg.HistogramBoundsMismatch)
+	}
+	switch cfg.SummaryQuantiles {
+	case SummaryQuantilesLatest, SummaryQuantilesMax:
+	default:
+		return fmt.Errorf("unknown summary-quantiles %q", cfg.SummaryQuantiles)
+	}
+	if cfg.SeriesExpiry < 0 {
+		return errors.New("series-expiry must not be negative")
+	}
+	if cfg.NameTemplate != "" && !strings.Contains(cfg.NameTemplate, "{stat}") {
+		return errors.New("name-template must contain {stat}")
+	}
+	for metricName, options := range cfg.MetricsOptions {
+		if options.NameTemplate != "" && !strings.Contains(options.NameTemplate, "{stat}") {
+			return fmt.Errorf("metric-options::%s: name-template must contain {stat}", metricName)
+		}
+		if err := cfg.checkStatisticNames(metricName, options.StatisticNames); err != nil {
+			return fmt.Errorf("metric-options::%s: statistic-names::%w", metricName, err)
+		}
+		switch options.CumulativeResets {
+		case "", CumulativeResetsAccumulate, CumulativeResetsRestart:
+		default:
+			return fmt.Errorf("metric-options::%s: unknown cumulative-resets %q", metricName, options.CumulativeResets)
+		}
+		switch options.CounterOutput {
+		case "", CounterOutputSum, CounterOutputRate:
+		default:
+			return fmt.Errorf("metric-options::%s: unknown counter-output %q", metricName, options.CounterOutput)
+		}
+		switch options.HistogramBoundsMismatch {
+		case "", HistogramBoundsMismatchDrop, HistogramBoundsMismatchCommon, HistogramBoundsMismatchUnion:
+		case HistogramBoundsMismatchConfigured:
+			if len(options.ExplicitBounds) == 0 {
+				return fmt.Errorf("metric-options::%s: explicit-bounds are required for histogram-bounds-mismatch %s", metricName, options.HistogramBoundsMismatch)
+			}
+		default:
+			return fmt.Errorf("metric-options::%s: unknown histogram-bounds-mismatch %q", metricName, options.HistogramBoundsMismatch)
+		}
+		switch options.SummaryQuantiles {
+		case "", SummaryQuantilesLatest, SummaryQuantilesMax:
+		default:
+			return fmt.Errorf("metric-options::%s: unknown summary-quantiles %q", metricName, options.SummaryQuantiles)
+		}
+		if len(options.KeepAttributes) > 0 && len(options.DropAttributes) > 0 {
+			return fmt.Errorf("metric-options::%s: keep-attributes and drop-attributes cannot be used together", metricName)
+		}
+		switch options.GaugeOutput {
+		case "", GaugeOutputStatistics:
+		case GaugeOutputHistogram:
+			if len(options.ExplicitBounds) == 0 {
+				return fmt.Errorf("metric-options::%s: explicit-bounds are required for gauge-output %s", metricName, options.GaugeOutput)
+			}
+		case GaugeOutputExponentialHistogram, GaugeOutputSummary:
+		default:
+			return fmt.Errorf("metric-options::%s: unknown gauge-output %q", metricName, options.GaugeOutput)
+		}
+		switch options.HistogramOutput {
+		case "", HistogramOutputHistogram:
+		case HistogramOutputStatistics:
+			if !hasCaseInsensitiveKey(cfg.HistogramStatistics, metricName) {
+				return fmt.Errorf("metric-options::%s: histogram-aggregations are required for histogram-output %s", metricName, options.HistogramOutput)
+			}
+		default:
+			return fmt.Errorf("metric-options::%s: unknown histogram-output %q", metricName, options.HistogramOutput)
+		}
+		if len(options.CoarseBounds) > 0 && options.MergeBuckets != 0 {
+			return fmt.Errorf("metric-options::%s: coarse-bounds and merge-buckets cannot be used together", metricName)
+		}
+		if options.MergeBuckets != 0 && options.MergeBuckets < 2 {
+			return fmt.Errorf("metric-options::%s: merge-buckets must be at least 2", metricName)
+		}
+		for i := 1; i < len(options.CoarseBounds); i++ {
+			if options.CoarseBounds[i-1] >= options.CoarseBounds[i] {
+				return fmt.Errorf("metric-options::%s: coarse-bounds must be strictly increasing", metricName)
+			}
+		}
+		if options.MaxBuckets != 0 && options.MaxBuckets < 2 {
+			return fmt.Errorf("metric-options::%s: max-buckets must be at least 2", metricName)
+		}
+		for i := 1; i < len(options.ExplicitBounds); i++ {
+			if options.ExplicitBounds[i-1] >= options.ExplicitBounds[i] {
+				return fmt.Errorf("metric-options::%s: explicit-bounds must be strictly increasing", metricName)
+			}
+		}
+	}
 	return nil
 }
+
+// The keys of statistic-names are matched against the token of the statistic, so they must be statistics
+// of the metric spelled the way they appear in names, like p99_9 rather than p99.9
+func (cfg *Config) checkStatisticNames(metricName string, statisticNames map[string]string) error {
+	sets := make([]StatisticSet, 0, 3)
+	if hasCaseInsensitiveKey(cfg.MetricStatistics, metricName) {
+		sets = append(sets, GaugeStatisticSet)
+	}
+	if hasCaseInsensitiveKey(cfg.SumStatistics, metricName) {
+		sets = append(sets, SumStatisticSet)
+	}
+	if hasCaseInsensitiveKey(cfg.HistogramStatistics, metricName) {
+		sets = append(sets, HistogramStatisticSet)
+	}
+	// Without explicit statistics the type of the metric is not known from the configuration
+	if len(sets) == 0 {
+		sets = append(sets, GaugeStatisticSet, SumStatisticSet, HistogramStatisticSet)
+	}
+	keys := make([]string, 0, len(statisticNames))
+	for key := range statisticNames {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// The token of a percentile has its decimal point replaced, which is undone to parse it
+		spelling := key
+		if strings.HasPrefix(key, "p") {
+			spelling = strings.Replace(key, "_", ".", 1)
+		}
+		var err error
+		for _, set := range sets {
+			var statistic Statistic
+			if statistic, err = set.Parse(spelling); err == nil {
+				if statistic.Name() != key {
+					err = fmt.Errorf("%s: statistic must be written as %q", key, statistic.Name())
+				}
+				break
+			}
+			err = fmt.Errorf("%s: %w", key, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tells if the name is a key of the map, ignoring case like the metric names are matched
+func hasCaseInsensitiveKey[V any](values map[string]V, name string) bool {
+	for key := range values {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// The metric names are matched ignoring case, so two keys that only differ in case would collide
+func checkCaseInsensitiveKeys[V any](values map[string]V) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	seen := make(map[string]string, len(names))
+	for _, name := range names {
+		if other, ok := seen[strings.ToLower(name)]; ok {
+			return fmt.Errorf("%q and %q only differ in case", other, name)
+		}
+		seen[strings.ToLower(name)] = name
+	}
+	return nil
+}
+
+// ReducesAttributes tells if the series of the metric are merged by attributes
+func (options MetricOptions) ReducesAttributes() bool {
+	return len(options.KeepAttributes) > 0 || len(options.DropAttributes) > 0
+}
@@ -0,0 +1,158 @@
+// Copyright (C) 2025 Bang & Olufsen A/S, Denmark
+//
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package reduceresolution
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfigStatistics(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		modify func(cfg *Config)
+		err    string
+	}{
+		{
+			"valid statistics",
+			func(cfg *Config) {
+				cfg.MetricStatistics = map[string][]string{"speed": {"max", "p99.9", "quantile(0.5)"}}
+			},
+			"",
+		},
+		{
+			"unknown statistic",
+			func(cfg *Config) {
+				cfg.MetricStatistics = map[string][]string{"speed": {"max", "maximum"}}
+			},
+			`gauge-aggregations::speed::1: unknown statistic "maximum"`,
+		},
+		{
+			"duplicate percentile",
+			func(cfg *Config) {
+				cfg.MetricStatistics = map[string][]string{"speed": {"p99.9", "quantile(0.999)"}}
+			},
+			`gauge-aggregations::speed::1: duplicate statistic "quantile(0.999)"`,
+		},
+		{
+			"names that only differ in case",
+			func(cfg *Config) {
+				cfg.MetricStatistics = map[string][]string{"Speed": {"max"}, "speed": {"min"}}
+			},
+			`gauge-aggregations: "Speed" and "speed" only differ in case`,
+		},
+		{
+			"passthrough with other statistics",
+			func(cfg *Config) {
+				cfg.DefaultGaugeAggregations = []string{"passthrough", "max"}
+			},
+			"default-gauge-aggregations::0: passthrough cannot be combined with other statistics",
+		},
+		{
+			"statistic name with a normalized percentile",
+			func(cfg *Config) {
+				cfg.MetricStatistics = map[string][]string{"speed": {"max", "p99.9"}}
+				cfg.MetricsOptions = map[string]MetricOptions{"speed": {StatisticNames: map[string]string{"max": "top_speed", "p99_9": "speed_tail"}}}
+			},
+			"",
+		},
+		{
+			"statistic name with an unnormalized percentile",
+			func(cfg *Config) {
+				cfg.MetricStatistics = map[string][]string{"speed": {"p99.9"}}
+				cfg.MetricsOptions = map[string]MetricOptions{"speed": {StatisticNames: map[string]string{"p99.9": "speed_tail"}}}
+			},
+			`metric-options::speed: statistic-names::p99.9: statistic must be written as "p99_9"`,
+		},
+		{
+			"statistic name with a typo",
+			func(cfg *Config) {
+				cfg.MetricsOptions = map[string]MetricOptions{"speed": {StatisticNames: map[string]string{"maxx": "top_speed"}}}
+			},
+			`metric-options::speed: statistic-names::maxx: unknown statistic "maxx"`,
+		},
+		{
+			"statistic name of another type of metric",
+			func(cfg *Config) {
+				cfg.SumStatistics = map[string][]string{"bytes": {"rate"}}
+				cfg.MetricsOptions = map[string]MetricOptions{"bytes": {StatisticNames: map[string]string{"max": "bytes_max"}}}
+			},
+			`metric-options::bytes: statistic-names::max: statistic "max" cannot be used for sums`,
+		},
+		{
+			"unknown statistic in a rule",
+			func(cfg *Config) {
+				cfg.GaugeAggregationRules = []GaugeAggregationRule{{Glob: "audio.*", Statistics: []string{"avg", "median"}}}
+			},
+			`gauge-aggregation-rules::0: statistics::1: unknown statistic "median"`,
+		},
+		{
+			"percentile of a sum",
+			func(cfg *Config) {
+				cfg.SumStatistics = map[string][]string{"bytes_sent": {"rate", "p99"}}
+			},
+			`sum-aggregations::bytes_sent::1: statistic "p99" cannot be used for sums`,
+		},
+		{
+			"valid statistics of sums and histograms",
+			func(cfg *Config) {
+				cfg.SumStatistics = map[string][]string{"bytes_sent": {"increase", "rate", "max_rate", "last", "sum"}}
+				cfg.HistogramStatistics = map[string][]string{"latency": {"p50", "p99", "mean", "count", "sum", "min", "max"}}
+			},
+			"",
+		},
+		{
+			"configured bounds mismatch without bounds",
+			func(cfg *Config) {
+				cfg.MetricsOptions = map[string]MetricOptions{"latency": {HistogramBoundsMismatch: HistogramBoundsMismatchConfigured}}
+			},
+			"metric-options::latency: explicit-bounds are required for histogram-bounds-mismatch configured",
+		},
+		{
+			"negative series expiry",
+			func(cfg *Config) {
+				cfg.SeriesExpiry = -1
+			},
+			"series-expiry must not be negative",
+		},
+		{
+			"unknown summary quantiles",
+			func(cfg *Config) {
+				cfg.MetricsOptions = map[string]MetricOptions{"request_duration": {SummaryQuantiles: "min"}}
+			},
+			`metric-options::request_duration: unknown summary-quantiles "min"`,
+		},
+		{
+			"coarse bounds with merged buckets",
+			func(cfg *Config) {
+				cfg.MetricsOptions = map[string]MetricOptions{"latency": {CoarseBounds: []float64{1, 10}, MergeBuckets: 2}}
+			},
+			"metric-options::latency: coarse-bounds and merge-buckets cannot be used together",
+		},
+		{
+			"histogram output without statistics",
+			func(cfg *Config) {
+				cfg.HistogramStatistics = map[string][]string{"Latency": {"p99"}}
+				cfg.MetricsOptions = map[string]MetricOptions{
+					"latency":       {HistogramOutput: HistogramOutputStatistics},
+					"response_size": {HistogramOutput: HistogramOutputStatistics},
+				}
+			},
+			"metric-options::response_size: histogram-aggregations are required for histogram-output statistics",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			test.modify(cfg)
+			err := cfg.Validate()
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
@@ -5,8 +5,11 @@
 package reduceresolution
 
 import (
+	"time"
+
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
 )
 
 type CounterValue interface {
@@ -23,40 +26,183 @@ type CounterAggregate[T CounterValue] struct {
 	lastTS      pcommon.Timestamp
 	aggregation pmetric.AggregationTemporality
 	monotonic   bool
+
+	// Only used for cumulative counters, to detect when the series was reset
+	resets        string
+	seriesStartTS pcommon.Timestamp
+	// Total of the series before the last reset, when the resets are accumulated
+	offset T
+
+	// The value of the latest datapoint, and the highest rate between two datapoints of the window
+	last       T
+	maxRate    float64
+	hasMaxRate bool
 }
 
-func CreateCounterAggregate[T CounterValue](metric pmetric.Metric, attributes pcommon.Map, startTS pcommon.Timestamp, lastTS pcommon.Timestamp, value T) *CounterAggregate[T] {
-	return &CounterAggregate[T]{
+func CreateCounterAggregate[T CounterValue](metric pmetric.Metric, attributes pcommon.Map, startTS pcommon.Timestamp, lastTS pcommon.Timestamp, value T, resets string) *CounterAggregate[T] {
+	aggregate := &CounterAggregate[T]{
 		value:       value,
 		name:        metric.Name(),
 		description: metric.Description(),
 		unit:        metric.Unit(),
-		attributes:  attributes,
+		attributes:  CopyAttributes(attributes),
 		startTS:     startTS,
 		lastTS:      lastTS,
 		aggregation: metric.Sum().AggregationTemporality(),
 		monotonic:   metric.Sum().IsMonotonic(),
+
+		resets:        resets,
+		seriesStartTS: startTS,
+
+		last: value,
+	}
+	// A delta is the increase since its own start, while the first cumulative value has nothing to compare with
+	if aggregate.aggregation == pmetric.AggregationTemporalityDelta {
+		aggregate.observeRate(value, startTS, lastTS)
 	}
+	return aggregate
+}
+
+// Keeps the highest rate per second of the increases within the window
+func (aggregate *CounterAggregate[T]) observeRate(increase T, fromTS pcommon.Timestamp, toTS pcommon.Timestamp) {
+	if fromTS == 0 || toTS <= fromTS {
+		return
+	}
+	rate := float64(increase) / toTS.AsTime().Sub(fromTS.AsTime()).Seconds()
+	if !aggregate.hasMaxRate || rate > aggregate.maxRate {
+		aggregate.maxRate = rate
+		aggregate.hasMaxRate = true
+	}
+}
+
+// A cumulative series was reset when its start changed, or when a monotonic value decreased
+func (aggregate *CounterAggregate[T]) isReset(startTS pcommon.Timestamp, value T) bool {
+	return startTS != aggregate.seriesStartTS || (aggregate.monotonic && value < aggregate.value-aggregate.offset)
 }
 
 func AggregateCounter[T CounterValue](aggregate *CounterAggregate[T], startTS pcommon.Timestamp, lastTS pcommon.Timestamp, value T) {
 	switch aggregate.aggregation {
 	case pmetric.AggregationTemporalityCumulative:
 		if aggregate.lastTS < lastTS {
-			aggregate.value = value
-			if startTS < aggregate.startTS {
+			previousValue, previousTS := aggregate.value, aggregate.lastTS
+			reset := aggregate.isReset(startTS, value)
+			if reset {
+				switch aggregate.resets {
+				case CumulativeResetsRestart:
+					// The increase before the reset is dropped, and the new series starts at the reset
+					aggregate.offset = 0
+					aggregate.startTS = startTS
+					if startTS == 0 || startTS < aggregate.lastTS {
+						aggregate.startTS = aggregate.lastTS
+					}
+				default:
+					aggregate.offset = aggregate.value
+				}
+				aggregate.seriesStartTS = startTS
+			} else if startTS < aggregate.startTS {
 				aggregate.startTS = startTS
 			}
+			aggregate.value = aggregate.offset + value
 			aggregate.lastTS = lastTS
+			aggregate.last = aggregate.value
+
+			increase := aggregate.value - previousValue
+			if reset && aggregate.resets == CumulativeResetsRestart {
+				increase = value
+			}
+			aggregate.observeRate(increase, previousTS, lastTS)
 		}
 	case pmetric.AggregationTemporalityDelta:
 		aggregate.value += value
 		if startTS < aggregate.startTS {
 			aggregate.startTS = startTS
 		}
+		if aggregate.lastTS <= lastTS {
+			aggregate.lastTS = lastTS
+			aggregate.last = value
+		}
+		aggregate.observeRate(value, startTS, lastTS)
+	}
+}
+
+// Returns an independent copy of the aggregate with a different set of attributes
+func CopyCounterAggregate[T CounterValue](aggregate *CounterAggregate[T], attributes pcommon.Map) *CounterAggregate[T] {
+	aggregateCopy := *aggregate
+	aggregateCopy.attributes = attributes
+	return &aggregateCopy
+}
+
+// Merges another series into the aggregate. The values are added for both temporalities,
+// since a cumulative aggregate already holds the latest value of its own series. The datapoints of the
+// series are not kept, so the max rate is the highest one of any merged series, not the one of their sum
+func MergeCounterAggregate[T CounterValue](aggregate *CounterAggregate[T], other *CounterAggregate[T]) {
+	aggregate.value += other.value
+	aggregate.last += other.last
+	if other.hasMaxRate && (!aggregate.hasMaxRate || other.maxRate > aggregate.maxRate) {
+		aggregate.maxRate = other.maxRate
+		aggregate.hasMaxRate = true
+	}
+	if other.startTS < aggregate.startTS {
+		aggregate.startTS = other.startTS
+	}
+	if aggregate.lastTS < other.lastTS {
+		aggregate.lastTS = other.lastTS
 	}
 }
 
+// ReduceCounterSeries merges the series of a counter whose attributes are reduced. The merged series of a cumulative
+// counter must not drop when one of its original series skips a window, so every original series is first turned
+// into its increase since the previous window, and the merged series is the running total of those increases
+func ReduceCounterSeries[T CounterValue](aggregates map[string]*CounterAggregate[T], scopeKey string, p *ReduceResolution) map[string]*CounterAggregate[T] {
+	reducesCumulative := func(aggregate *CounterAggregate[T]) bool {
+		return aggregate.aggregation == pmetric.AggregationTemporalityCumulative && p.MetricOptions(aggregate.name).ReducesAttributes()
+	}
+
+	p.seriesMutex.Lock()
+	for key, aggregate := range aggregates {
+		if !reducesCumulative(aggregate) {
+			continue
+		}
+		state := p.seriesState("original|" + scopeKey + key)
+		value := float64(aggregate.value)
+		increase := value
+		if state.seen && state.startTS == aggregate.startTS && (!aggregate.monotonic || value >= state.doubleValue) {
+			increase -= state.doubleValue
+		}
+		state.seen = true
+		state.startTS = aggregate.startTS
+		state.doubleValue = value
+		aggregate.value = T(increase)
+	}
+	p.seriesMutex.Unlock()
+
+	reduced := ReduceSeries(aggregates, p,
+		func(aggregate *CounterAggregate[T]) (string, pcommon.Map) {
+			return aggregate.name, aggregate.attributes
+		},
+		CopyCounterAggregate[T],
+		MergeCounterAggregate[T])
+
+	p.seriesMutex.Lock()
+	defer p.seriesMutex.Unlock()
+	for key, aggregate := range reduced {
+		if !reducesCumulative(aggregate) {
+			continue
+		}
+		// The merged series keeps the start of its first window, as its original series come and go
+		state := p.seriesState("reduced|" + scopeKey + key)
+		if !state.seen {
+			state.seen = true
+			state.startTS = aggregate.startTS
+		}
+		state.doubleValue += float64(aggregate.value)
+		aggregate.value = T(state.doubleValue)
+		aggregate.last = aggregate.value
+		aggregate.startTS = state.startTS
+	}
+	return reduced
+}
+
 func CreateCounterMetrics[T GaugeValue](scope pmetric.ScopeMetrics, aggregate *CounterAggregate[T], aggregationTS pcommon.Timestamp) {
 	metric_value := scope.Metrics().AppendEmpty()
 	metric_value.SetName(aggregate.name)
@@ -76,3 +222,98 @@ func CreateCounterMetrics[T GaugeValue](scope pmetric.ScopeMetrics, aggregate *C
 		counter_dp.SetDoubleValue(v)
 	}
 }
+
+// CounterIncrease returns the increase of the counter within the window, and how long it took. A cumulative
+// counter is compared with its value in the previous window, unless this is its first window or it was reset,
+// then the increase is counted from the start of the series
+func CounterIncrease[T CounterValue](aggregate *CounterAggregate[T], seriesKey string, p *ReduceResolution) (float64, time.Duration) {
+	increase := float64(aggregate.value)
+	startTS := aggregate.startTS
+	if aggregate.aggregation == pmetric.AggregationTemporalityCumulative {
+		p.seriesMutex.Lock()
+		state := p.seriesState("increase|" + seriesKey)
+		if state.seen && state.startTS == aggregate.startTS && (!aggregate.monotonic || increase >= state.doubleValue) {
+			increase -= state.doubleValue
+			startTS = state.lastTS
+		}
+		state.seen = true
+		state.startTS = aggregate.startTS
+		state.doubleValue = float64(aggregate.value)
+		state.lastTS = aggregate.lastTS
+		p.seriesMutex.Unlock()
+	}
+	return increase, aggregate.lastTS.AsTime().Sub(startTS.AsTime())
+}
+
+// Unit of the rate per second of a counter, following UCUM like the unit of the counter
+func RateUnit(unit string) string {
+	if unit == "" {
+		return "1/s"
+	}
+	return unit + "/s"
+}
+
+// CreateSumMetrics emits the statistics configured for a counter, or the counter itself when there are none
+func CreateSumMetrics[T CounterValue](scope pmetric.ScopeMetrics, statisticsMetrics StatisticsMetrics, aggregate *CounterAggregate[T], aggregationTS pcommon.Timestamp, seriesKey string, p *ReduceResolution) {
+	statistics, ok := p.SumStatistics(aggregate.name)
+	if !ok {
+		statistics = []Statistic{StatisticSum}
+		if p.MetricOptions(aggregate.name).CounterOutput == CounterOutputRate {
+			statistics = []Statistic{StatisticRate}
+		}
+	}
+
+	emitter := &StatisticsEmitter{
+		p:                   p,
+		scope:               scope,
+		statisticsMetrics:   statisticsMetrics,
+		name:                aggregate.name,
+		description:         aggregate.description,
+		unit:                aggregate.unit,
+		attributes:          aggregate.attributes,
+		startTS:             aggregate.startTS,
+		defaultTemplate:     DefaultNameTemplate,
+		attributeMetricName: aggregate.name + "_" + p.Config.StatisticAttribute,
+	}
+
+	// The increase of a cumulative counter updates the state of the series, so it is only calculated once
+	var increase float64
+	var elapsed time.Duration
+	calculated := false
+	windowIncrease := func() (float64, time.Duration) {
+		if !calculated {
+			increase, elapsed = CounterIncrease(aggregate, seriesKey, p)
+			calculated = true
+		}
+		return increase, elapsed
+	}
+
+	for _, statistic := range statistics {
+		switch statistic {
+		case StatisticSum:
+			CreateCounterMetrics(scope, aggregate, aggregationTS)
+		case StatisticIncrease:
+			increase, _ := windowIncrease()
+			emitter.DataPoint("increase", aggregate.unit, aggregationTS).SetDoubleValue(increase)
+		case StatisticRate:
+			increase, elapsed := windowIncrease()
+			if aggregate.startTS == 0 || elapsed <= 0 {
+				p.Logger.Debug("Rate not emitted, the counter has no elapsed time", zap.String("metric", aggregate.name))
+				continue
+			}
+			emitter.DataPoint("rate", RateUnit(aggregate.unit), aggregationTS).SetDoubleValue(increase / elapsed.Seconds())
+		case StatisticMaxRate:
+			if aggregate.hasMaxRate {
+				emitter.DataPoint("max_rate", RateUnit(aggregate.unit), aggregationTS).SetDoubleValue(aggregate.maxRate)
+			}
+		case StatisticLast:
+			gauge_dp := emitter.DataPoint("last", aggregate.unit, aggregate.lastTS)
+			switch v := any(aggregate.last).(type) {
+			case int64:
+				gauge_dp.SetIntValue(v)
+			case float64:
+				gauge_dp.SetDoubleValue(v)
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright (C) 2025 Bang & Olufsen A/S, Denmark
+//
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package reduceresolution
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// Lowest and highest observation of a histogram, either of which may be unknown
+type MinMax struct {
+	min    float64
+	max    float64
+	hasMin bool
+	hasMax bool
+}
+
+// Datapoints that have a minimum and a maximum, like histograms and exponential histograms
+type minMaxDataPoint interface {
+	Min() float64
+	HasMin() bool
+	Max() float64
+	HasMax() bool
+}
+
+func DataPointMinMax(value minMaxDataPoint) MinMax {
+	return MinMax{
+		min:    value.Min(),
+		max:    value.Max(),
+		hasMin: value.HasMin(),
+		hasMax: value.HasMax(),
+	}
+}
+
+// Keeps the lowest minimum and the highest maximum of both, ignoring the ones that are not set
+func (minMax *MinMax) MergeMinMax(other MinMax) {
+	if other.hasMin && (!minMax.hasMin || other.min < minMax.min) {
+		minMax.min = other.min
+		minMax.hasMin = true
+	}
+	if other.hasMax && (!minMax.hasMax || other.max > minMax.max) {
+		minMax.max = other.max
+		minMax.hasMax = true
+	}
+}
+
+// Datapoints of a cumulative series that counts its observations, like histograms and summaries
+type cumulativeDataPoint interface {
+	StartTimestamp() pcommon.Timestamp
+	Count() uint64
+}
+
+// State that is kept to handle the resets of a cumulative series, see cumulative-resets
+type CumulativeSeries[A any] struct {
+	resets        string
+	seriesStartTS pcommon.Timestamp
+	// Totals of the series before the last reset, when the resets are accumulated
+	offset *A
+	// Count of the offset, which is left out of the count the datapoints are compared with
+	offsetCount uint64
+}
+
+func CreateCumulativeSeries[A any](resets string, startTS pcommon.Timestamp) CumulativeSeries[A] {
+	return CumulativeSeries[A]{
+		resets:        resets,
+		seriesStartTS: startTS,
+	}
+}
+
+// A cumulative series was reset when its start changed, or when its count decreased.
+// The count is the one of the aggregate, which includes the offset
+func (series *CumulativeSeries[A]) IsReset(value cumulativeDataPoint, count uint64) bool {
+	return value.StartTimestamp() != series.seriesStartTS || value.Count() < count-series.offsetCount
+}
+
+// Whether the datapoint is added on top of an offset, so both need to share their buckets
+func (series *CumulativeSeries[A]) Accumulates(reset bool) bool {
+	return series.offset != nil || (reset && series.resets != CumulativeResetsRestart)
+}
+
+// Moves the series on to its next datapoint. After a reset, either the series starts again at the reset,
+// or the aggregate so far is kept as the offset, which snapshot returns without an offset of its own.
+// Otherwise the start of the series moves back when the datapoint started earlier
+func (series *CumulativeSeries[A]) Continue(value cumulativeDataPoint, reset bool, startTS *pcommon.Timestamp, lastTS pcommon.Timestamp, count uint64, snapshot func() *A) {
+	if !reset {
+		if value.StartTimestamp() < *startTS {
+			*startTS = value.StartTimestamp()
+		}
+		return
+	}
+	switch series.resets {
+	case CumulativeResetsRestart:
+		// The observations before the reset are dropped, and the new series starts at the reset
+		series.offset = nil
+		series.offsetCount = 0
+		*startTS = value.StartTimestamp()
+		if value.StartTimestamp() == 0 || value.StartTimestamp() < lastTS {
+			*startTS = lastTS
+		}
+	default:
+		series.offset = snapshot()
+		series.offsetCount = count
+	}
+	series.seriesStartTS = value.StartTimestamp()
+}
@@ -0,0 +1,265 @@
+// Copyright (C) 2025 Bang & Olufsen A/S, Denmark
+//
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package reduceresolution
+
+import (
+	"math"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+const (
+	// Highest resolution used for new exponential histograms, it is reduced as the values spread
+	ExponentialHistogramMaxScale = 20
+	// Lowest resolution, where every bucket is 2^(2^10) times bigger than the previous one
+	ExponentialHistogramMinScale = -10
+	// Maximum number of buckets for each sign, when no other is configured
+	DefaultExponentialMaxBuckets = 160
+)
+
+// ExponentialBuckets holds the counts of a range of consecutive buckets, starting at the offset
+type ExponentialBuckets struct {
+	offset int32
+	counts []uint64
+}
+
+// ExponentialHistogram keeps values in buckets whose bounds grow by a factor of 2^(2^-scale).
+// The scale starts at its highest resolution, and is reduced whenever the values would not fit
+// in the maximum number of buckets, in the same way as the exponential histogram aggregation
+// of the OpenTelemetry SDKs
+type ExponentialHistogram struct {
+	maxBuckets int
+	scale      int32
+	zeroCount  uint64
+	positive   ExponentialBuckets
+	negative   ExponentialBuckets
+}
+
+func CreateExponentialHistogram(maxBuckets int) *ExponentialHistogram {
+	return &ExponentialHistogram{
+		maxBuckets: maxBuckets,
+		scale:      ExponentialHistogramMaxScale,
+	}
+}
+
+// Creates a histogram with the buckets of a datapoint, at a lower scale when they do not fit in the maximum number of buckets.
+// The zero threshold of the datapoint is not read, as pdata does not expose it in the version the collector is built with
+func CreateExponentialHistogramFromDataPoint(dp pmetric.ExponentialHistogramDataPoint, maxBuckets int) *ExponentialHistogram {
+	histogram := &ExponentialHistogram{
+		maxBuckets: maxBuckets,
+		scale:      dp.Scale(),
+		zeroCount:  dp.ZeroCount(),
+		positive:   ExponentialBuckets{offset: dp.Positive().Offset(), counts: dp.Positive().BucketCounts().AsRaw()},
+		negative:   ExponentialBuckets{offset: dp.Negative().Offset(), counts: dp.Negative().BucketCounts().AsRaw()},
+	}
+	change := histogram.scaleChange(&histogram.positive, histogram.positive.offset, histogram.positive.offset)
+	if negativeChange := histogram.scaleChange(&histogram.negative, histogram.negative.offset, histogram.negative.offset); negativeChange > change {
+		change = negativeChange
+	}
+	histogram.Downscale(change)
+	return histogram
+}
+
+// Index of the bucket a positive value falls into, where the bucket i is (base^i, base^(i+1)]
+func ExponentialIndex(value float64, scale int32) int32 {
+	if scale <= 0 {
+		// Exact for powers of two, which are the upper bound of their bucket
+		frac, exp := math.Frexp(value)
+		index := int32(exp - 1)
+		if frac == 0.5 {
+			index--
+		}
+		return index >> -scale
+	}
+	return int32(math.Ceil(math.Log2(value)*math.Ldexp(1, int(scale)))) - 1
+}
+
+func (histogram *ExponentialHistogram) Add(value float64) {
+	histogram.AddCount(value, 1)
+}
+
+func (histogram *ExponentialHistogram) AddCount(value float64, count uint64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	if value == 0 {
+		histogram.zeroCount += count
+		return
+	}
+
+	buckets := &histogram.positive
+	if value < 0 {
+		buckets = &histogram.negative
+		value = -value
+	}
+
+	index := ExponentialIndex(value, histogram.scale)
+	if change := histogram.scaleChange(buckets, index, index); change > 0 {
+		histogram.Downscale(change)
+		index = ExponentialIndex(value, histogram.scale)
+	}
+	buckets.increment(index, count)
+}
+
+// Merges another histogram into this one, at the lowest of both scales or lower if needed
+func (histogram *ExponentialHistogram) Merge(other *ExponentialHistogram) {
+	otherCopy := other.Copy()
+	if otherCopy.scale < histogram.scale {
+		histogram.Downscale(histogram.scale - otherCopy.scale)
+	} else if histogram.scale < otherCopy.scale {
+		otherCopy.Downscale(otherCopy.scale - histogram.scale)
+	}
+
+	change := int32(0)
+	for _, pair := range [][2]*ExponentialBuckets{
+		{&histogram.positive, &otherCopy.positive},
+		{&histogram.negative, &otherCopy.negative},
+	} {
+		if len(pair[1].counts) == 0 {
+			continue
+		}
+		low, high := pair[1].offset, pair[1].offset+int32(len(pair[1].counts))-1
+		if bucketChange := histogram.scaleChange(pair[0], low, high); bucketChange > change {
+			change = bucketChange
+		}
+	}
+	if change > 0 {
+		histogram.Downscale(change)
+		otherCopy.Downscale(change)
+	}
+
+	histogram.zeroCount += otherCopy.zeroCount
+	histogram.positive.add(&otherCopy.positive)
+	histogram.negative.add(&otherCopy.negative)
+}
+
+// Returns the counts that were added since an earlier state of the same cumulative histogram, at the lowest
+// of both scales, or false when a bucket of the earlier state decreased or is not there anymore
+func (histogram *ExponentialHistogram) Subtract(previous *ExponentialHistogram) (*ExponentialHistogram, bool) {
+	delta := histogram.Copy()
+	previousCopy := previous.Copy()
+	if previousCopy.scale < delta.scale {
+		delta.Downscale(delta.scale - previousCopy.scale)
+	} else if delta.scale < previousCopy.scale {
+		previousCopy.Downscale(previousCopy.scale - delta.scale)
+	}
+
+	if delta.zeroCount < previousCopy.zeroCount {
+		return nil, false
+	}
+	delta.zeroCount -= previousCopy.zeroCount
+	for _, pair := range [][2]*ExponentialBuckets{
+		{&delta.positive, &previousCopy.positive},
+		{&delta.negative, &previousCopy.negative},
+	} {
+		if !pair[0].subtract(pair[1]) {
+			return nil, false
+		}
+	}
+	return delta, true
+}
+
+func (histogram *ExponentialHistogram) Copy() *ExponentialHistogram {
+	histogramCopy := *histogram
+	histogramCopy.positive.counts = append([]uint64(nil), histogram.positive.counts...)
+	histogramCopy.negative.counts = append([]uint64(nil), histogram.negative.counts...)
+	return &histogramCopy
+}
+
+// Reduces the scale, merging every 2^change consecutive buckets into one
+func (histogram *ExponentialHistogram) Downscale(change int32) {
+	if histogram.scale-change < ExponentialHistogramMinScale {
+		change = histogram.scale - ExponentialHistogramMinScale
+	}
+	if change <= 0 {
+		return
+	}
+	histogram.positive.downscale(change)
+	histogram.negative.downscale(change)
+	histogram.scale -= change
+}
+
+// How much the scale must be reduced so that the buckets also cover the indexes from low to high
+func (histogram *ExponentialHistogram) scaleChange(buckets *ExponentialBuckets, low int32, high int32) int32 {
+	if len(buckets.counts) > 0 {
+		if buckets.offset < low {
+			low = buckets.offset
+		}
+		if last := buckets.offset + int32(len(buckets.counts)) - 1; last > high {
+			high = last
+		}
+	}
+
+	change := int32(0)
+	for int(int64(high)-int64(low)) >= histogram.maxBuckets && histogram.scale-change > ExponentialHistogramMinScale {
+		high >>= 1
+		low >>= 1
+		change++
+	}
+	return change
+}
+
+func (buckets *ExponentialBuckets) increment(index int32, count uint64) {
+	if len(buckets.counts) == 0 {
+		buckets.offset = index
+		buckets.counts = []uint64{count}
+		return
+	}
+	if index < buckets.offset {
+		buckets.counts = append(make([]uint64, buckets.offset-index), buckets.counts...)
+		buckets.offset = index
+	}
+	if position := int(index - buckets.offset); position >= len(buckets.counts) {
+		buckets.counts = append(buckets.counts, make([]uint64, position-len(buckets.counts)+1)...)
+	}
+	buckets.counts[index-buckets.offset] += count
+}
+
+func (buckets *ExponentialBuckets) add(other *ExponentialBuckets) {
+	for i, count := range other.counts {
+		if count > 0 {
+			buckets.increment(other.offset+int32(i), count)
+		}
+	}
+}
+
+func (buckets *ExponentialBuckets) subtract(other *ExponentialBuckets) bool {
+	for i, count := range other.counts {
+		if count == 0 {
+			continue
+		}
+		position := int(other.offset+int32(i)) - int(buckets.offset)
+		if position < 0 || position >= len(buckets.counts) || buckets.counts[position] < count {
+			return false
+		}
+		buckets.counts[position] -= count
+	}
+	return true
+}
+
+func (buckets *ExponentialBuckets) downscale(change int32) {
+	if len(buckets.counts) == 0 {
+		return
+	}
+	offset := buckets.offset >> change
+	last := (buckets.offset + int32(len(buckets.counts)) - 1) >> change
+	counts := make([]uint64, last-offset+1)
+	for i, count := range buckets.counts {
+		counts[((buckets.offset+int32(i))>>change)-offset] += count
+	}
+	buckets.offset = offset
+	buckets.counts = counts
+}
+
+// Sets the scale and the buckets of the datapoint, the count, sum, min and max are left untouched.
+// The zero threshold cannot be set with the pdata version the collector is built with, so it stays 0
+func (histogram *ExponentialHistogram) CopyToDataPoint(dp pmetric.ExponentialHistogramDataPoint) {
+	dp.SetScale(histogram.scale)
+	dp.SetZeroCount(histogram.zeroCount)
+	dp.Positive().SetOffset(histogram.positive.offset)
+	dp.Positive().BucketCounts().FromRaw(histogram.positive.counts)
+	dp.Negative().SetOffset(histogram.negative.offset)
+	dp.Negative().BucketCounts().FromRaw(histogram.negative.counts)
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2025 Bang & Olufsen A/S, Denmark
+//
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package reduceresolution
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+type ExponentialHistogramAggregate struct {
+	MinMax
+	count       uint64
+	sum         float64
+	buckets     *ExponentialHistogram
+	name        string
+	unit        string
+	description string
+	attributes  pcommon.Map
+	startTS     pcommon.Timestamp
+	lastTS      pcommon.Timestamp
+	aggregation pmetric.AggregationTemporality
+
+	// Only used for cumulative histograms, to detect when the series was reset
+	CumulativeSeries[ExponentialHistogramAggregate]
+}
+
+func CreateExponentialHistogramAggregate(metric pmetric.Metric, value pmetric.ExponentialHistogramDataPoint, resets string, maxBuckets int) *ExponentialHistogramAggregate {
+	return &ExponentialHistogramAggregate{
+		count:       value.Count(),
+		sum:         value.Sum(),
+		MinMax:      DataPointMinMax(value),
+		buckets:     CreateExponentialHistogramFromDataPoint(value, maxBuckets),
+		name:        metric.Name(),
+		unit:        metric.Unit(),
+		description: metric.Description(),
+		attributes:  CopyAttributes(value.Attributes()),
+		startTS:     value.StartTimestamp(),
+		lastTS:      value.Timestamp(),
+		aggregation: metric.ExponentialHistogram().AggregationTemporality(),
+
+		CumulativeSeries: CreateCumulativeSeries[ExponentialHistogramAggregate](resets, value.StartTimestamp()),
+	}
+}
+
+// Aggregates a datapoint into the series. The buckets always fit, since both are brought to the lowest of their scales
+func AggregateExponentialHistogram(aggregate *ExponentialHistogramAggregate, value pmetric.ExponentialHistogramDataPoint) {
+	switch aggregate.aggregation {
+	case pmetric.AggregationTemporalityCumulative:
+		if aggregate.lastTS < value.Timestamp() {
+			reset := aggregate.IsReset(value, aggregate.count)
+			aggregate.Continue(value, reset, &aggregate.startTS, aggregate.lastTS, aggregate.count, func() *ExponentialHistogramAggregate {
+				offset := CopyExponentialHistogramAggregate(aggregate, aggregate.attributes)
+				offset.offset = nil
+				return offset
+			})
+			aggregate.count = value.Count()
+			aggregate.sum = value.Sum()
+			aggregate.MinMax = DataPointMinMax(value)
+			aggregate.buckets = CreateExponentialHistogramFromDataPoint(value, aggregate.buckets.maxBuckets)
+			if aggregate.offset != nil {
+				aggregate.buckets.Merge(aggregate.offset.buckets)
+				aggregate.count += aggregate.offset.count
+				aggregate.sum += aggregate.offset.sum
+				aggregate.MergeMinMax(aggregate.offset.MinMax)
+			}
+			aggregate.lastTS = value.Timestamp()
+		}
+	case pmetric.AggregationTemporalityDelta:
+		aggregate.buckets.Merge(CreateExponentialHistogramFromDataPoint(value, aggregate.buckets.maxBuckets))
+		aggregate.count += value.Count()
+		aggregate.sum += value.Sum()
+		aggregate.MergeMinMax(DataPointMinMax(value))
+		if value.StartTimestamp() < aggregate.startTS {
+			aggregate.startTS = value.StartTimestamp()
+		}
+		if aggregate.lastTS < value.Timestamp() {
+			aggregate.lastTS = value.Timestamp()
+		}
+	}
+}
+
+// Returns an independent copy of the aggregate with a different set of attributes
+func CopyExponentialHistogramAggregate(aggregate *ExponentialHistogramAggregate, attributes pcommon.Map) *ExponentialHistogramAggregate {
+	aggregateCopy := *aggregate
+	aggregateCopy.buckets = aggregate.buckets.Copy()
+	aggregateCopy.attributes = attributes
+	return &aggregateCopy
+}
+
+// Merges another series into the aggregate
+func MergeExponentialHistogramAggregate(aggregate *ExponentialHistogramAggregate, other *ExponentialHistogramAggregate) {
+	aggregate.buckets.Merge(other.buckets)
+	aggregate.count += other.count
+	aggregate.sum += other.sum
+	aggregate.MergeMinMax(other.MinMax)
+	if other.startTS < aggregate.startTS {
+		aggregate.startTS = other.startTS
+	}
+	if aggregate.lastTS < other.lastTS {
+		aggregate.lastTS = other.lastTS
+	}
+}
+
+func CreateExponentialHistogramMetrics(scope pmetric.ScopeMetrics, aggregate *ExponentialHistogramAggregate, aggregationTS pcommon.Timestamp) {
+	metric := scope.Metrics().AppendEmpty()
+	metric.SetName(aggregate.name)
+	metric.SetUnit(aggregate.unit)
+	metric.SetDescription(aggregate.description)
+	histogram := metric.SetEmptyExponentialHistogram()
+	histogram.SetAggregationTemporality(aggregate.aggregation)
+	histogram_dp := histogram.DataPoints().AppendEmpty()
+	histogram_dp.SetStartTimestamp(aggregate.startTS)
+	histogram_dp.SetTimestamp(aggregationTS)
+	aggregate.attributes.CopyTo(histogram_dp.Attributes())
+	histogram_dp.SetCount(aggregate.count)
+	histogram_dp.SetSum(aggregate.sum)
+	if aggregate.hasMin {
+		histogram_dp.SetMin(aggregate.min)
+	}
+	if aggregate.hasMax {
+		histogram_dp.SetMax(aggregate.max)
+	}
+	aggregate.buckets.CopyToDataPoint(histogram_dp)
+}
@@ -24,7 +24,16 @@ func NewFactory() processor.Factory {
 
 // createDefaultConfig creates the default configuration for the processor
 func createDefaultConfig() component.Config {
-	return &Config{}
+	return &Config{
+		DefaultGaugeAggregations: []string{string(StatisticAbsMax), string(StatisticAbsMin)},
+		RelativeAccuracy:         DefaultRelativeAccuracy,
+		StatisticNaming:          StatisticNamingSuffix,
+		StatisticAttribute:       DefaultStatisticAttribute,
+		CumulativeResets:         CumulativeResetsAccumulate,
+		HistogramBoundsMismatch:  HistogramBoundsMismatchDrop,
+		SummaryQuantiles:         SummaryQuantilesLatest,
+		SeriesExpiry:             DefaultSeriesExpiry,
+	}
 }
 
 // createMetricsProcessor creates a new instance of the metric logging processor
@@ -37,14 +46,48 @@ func createMetricsProcessor(
 	c := config.(*Config)
 
 	var processedConfig ProcessedConfig
-	processedConfig.MetricsStatistics = map[string][]string{}
-	for metricName, statisticsList := range c.MetricStatistics {
-		processedConfig.MetricsStatistics[strings.ToLower(metricName)] = statisticsList
+	var err error
+	if processedConfig.MetricsStatistics, err = processStatistics(c.MetricStatistics, GaugeStatisticSet); err != nil {
+		return nil, err
+	}
+	if processedConfig.SumStatistics, err = processStatistics(c.SumStatistics, SumStatisticSet); err != nil {
+		return nil, err
+	}
+	if processedConfig.HistogramStatistics, err = processStatistics(c.HistogramStatistics, HistogramStatisticSet); err != nil {
+		return nil, err
+	}
+	for _, rule := range c.GaugeAggregationRules {
+		processedRule, err := ProcessGaugeAggregationRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		processedConfig.GaugeAggregationRules = append(processedConfig.GaugeAggregationRules, processedRule)
+	}
+	defaultStatistics, err := GaugeStatisticSet.ParseStatistics(c.DefaultGaugeAggregations)
+	if err != nil {
+		return nil, err
 	}
+	processedConfig.DefaultGaugeStatistics = defaultStatistics
+	processedConfig.MetricsOptions = map[string]MetricOptions{}
+	for metricName, options := range c.MetricsOptions {
+		processedConfig.MetricsOptions[strings.ToLower(metricName)] = options
+	}
+	processedConfig.Interval = c.Interval
+	processedConfig.RelativeAccuracy = c.RelativeAccuracy
+	processedConfig.StatisticNaming = c.StatisticNaming
+	processedConfig.StatisticAttribute = c.StatisticAttribute
+	processedConfig.NameTemplate = c.NameTemplate
+	processedConfig.CumulativeResets = c.CumulativeResets
+	processedConfig.OutputTemporality = c.OutputTemporality
+	processedConfig.HistogramBoundsMismatch = c.HistogramBoundsMismatch
+	processedConfig.SummaryQuantiles = c.SummaryQuantiles
+	processedConfig.SeriesExpiry = c.SeriesExpiry
+	processedConfig.RollupResourceAttributes = c.RollupResourceAttributes
 
 	logProcessor := &ReduceResolution{
-		Logger: settings.Logger,
-		Config: processedConfig,
+		Logger:       settings.Logger,
+		Config:       processedConfig,
+		nextConsumer: nextConsumer,
 	}
 
 	return processorhelper.NewMetricsProcessor(
@@ -53,5 +96,20 @@ func createMetricsProcessor(
 		config,
 		nextConsumer,
 		logProcessor.ProcessMetrics,
+		processorhelper.WithStart(logProcessor.Start),
+		processorhelper.WithShutdown(logProcessor.Shutdown),
 		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: false}))
 }
+
+// processStatistics parses the statistics of every metric, keyed by the lower case name of the metric
+func processStatistics(metricsStatistics map[string][]string, set StatisticSet) (map[string][]Statistic, error) {
+	processedStatistics := map[string][]Statistic{}
+	for metricName, statisticsList := range metricsStatistics {
+		statistics, err := set.ParseStatistics(statisticsList)
+		if err != nil {
+			return nil, err
+		}
+		processedStatistics[strings.ToLower(metricName)] = statistics
+	}
+	return processedStatistics, nil
+}
@@ -5,7 +5,8 @@
 package reduceresolution
 
 import (
-	"strings"
+	"math"
+	"sort"
 
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
@@ -35,9 +36,108 @@ type GaugeAggregate[T GaugeValue] struct {
 	unit        string
 	attributes  pcommon.Map
 	startTS     pcommon.Timestamp
+	// Running mean and sum of squared differences from it (Welford's algorithm),
+	// which give the variance without the cancellation errors of a sum of squares.
+	// The mean is relative to the first value, so large offsets do not eat its precision
+	shift float64
+	mean  float64
+	m2    float64
+	// Values with the earliest and latest datapoint timestamps
+	first   T
+	firstTS pcommon.Timestamp
+	last    T
+	lastTS  pcommon.Timestamp
+	// Only kept when a quantile is requested for the metric
+	sketch *QuantileSketch
+	// Only kept when the time weighted average is requested for the metric. There is one
+	// running average for every original series that was merged into the aggregate
+	timeWeighted []TimeWeightedSeries
+	// Only kept when the gauge is emitted as a histogram
+	explicitBounds []float64
+	bucketCounts   []uint64
+	// Only kept when the gauge is emitted as an exponential histogram
+	exponentialHistogram *ExponentialHistogram
 }
 
-func CreateGaugeAggregate[T GaugeValue](metric pmetric.Metric, attributes pcommon.Map, startTS pcommon.Timestamp, value T) *GaugeAggregate[T] {
+// TimeWeightedSeries accumulates the time weighted average of a single original series, one sample at a time
+type TimeWeightedSeries struct {
+	// Resource and metric key of the original series, which identifies it across windows
+	key         string
+	firstTS     pcommon.Timestamp
+	lastTS      pcommon.Timestamp
+	lastValue   float64
+	weightedSum float64
+}
+
+// Weights the previous value by how long it was in effect, until this sample. A sample older than the
+// latest one cannot be weighted without keeping every sample, so it is left out of the average
+func (series *TimeWeightedSeries) Add(ts pcommon.Timestamp, value float64) {
+	if ts < series.lastTS {
+		return
+	}
+	series.weightedSum += series.lastValue * float64(ts-series.lastTS)
+	series.lastTS = ts
+	series.lastValue = value
+}
+
+// GaugeSettings holds what needs to be tracked for a gauge, besides the basic statistics
+type GaugeSettings struct {
+	// Relative accuracy of the quantiles, or 0 when no quantile is requested
+	quantileAccuracy float64
+	// Whether the time weighted average is computed
+	timeWeighted bool
+	// Bucket bounds of the histogram the gauge is emitted as, if any
+	explicitBounds []float64
+	// Maximum number of buckets of the exponential histogram the gauge is emitted as, if any
+	exponentialMaxBuckets int
+}
+
+// Finds out what needs to be tracked for a gauge, based on the statistics configured for it
+func (p *ReduceResolution) GaugeSettings(name string) GaugeSettings {
+	var settings GaugeSettings
+	options := p.MetricOptions(name)
+	switch options.GaugeOutput {
+	case GaugeOutputHistogram:
+		settings.explicitBounds = options.ExplicitBounds
+	case GaugeOutputExponentialHistogram:
+		settings.exponentialMaxBuckets = p.ExponentialMaxBuckets(name)
+	}
+	for _, statistic := range p.GaugeStatistics(name) {
+		if _, ok := statistic.Quantile(); ok {
+			settings.quantileAccuracy = p.Config.RelativeAccuracy
+			if settings.quantileAccuracy == 0 {
+				settings.quantileAccuracy = DefaultRelativeAccuracy
+			}
+		}
+		if statistic == StatisticTWA {
+			settings.timeWeighted = true
+		}
+	}
+	return settings
+}
+
+// The resource key identifies the resource the series was received from, which stays apart from the other
+// resources of a cohort it is merged into
+func CreateGaugeAggregate[T GaugeValue](metric pmetric.Metric, attributes pcommon.Map, startTS pcommon.Timestamp, ts pcommon.Timestamp, value T, settings GaugeSettings, resourceKey string) *GaugeAggregate[T] {
+	var sketch *QuantileSketch
+	if settings.quantileAccuracy > 0 {
+		sketch = CreateQuantileSketch(settings.quantileAccuracy)
+		sketch.Add(float64(value))
+	}
+	var timeWeighted []TimeWeightedSeries
+	if settings.timeWeighted {
+		timeWeighted = []TimeWeightedSeries{{resourceKey + "|" + CreateMetricKey(metric, attributes), ts, ts, float64(value), 0}}
+	}
+	var bucketCounts []uint64
+	if settings.explicitBounds != nil {
+		bucketCounts = make([]uint64, len(settings.explicitBounds)+1)
+		bucketCounts[BucketIndex(settings.explicitBounds, float64(value))]++
+	}
+	var exponentialHistogram *ExponentialHistogram
+	if settings.exponentialMaxBuckets > 0 {
+		exponentialHistogram = CreateExponentialHistogram(settings.exponentialMaxBuckets)
+		exponentialHistogram.Add(float64(value))
+	}
 	return &GaugeAggregate[T]{
 		count:       1,
 		max:         value,
@@ -48,14 +148,31 @@ func CreateGaugeAggregate[T GaugeValue](metric pmetric.Metric, attributes pcommo
 		name:        metric.Name(),
 		description: metric.Description(),
 		unit:        metric.Unit(),
-		attributes:  attributes,
+		attributes:  CopyAttributes(attributes),
 		startTS:     startTS,
+		shift:       float64(value),
+		first:       value,
+		firstTS:     ts,
+		last:        value,
+		lastTS:      ts,
+		sketch:      sketch,
+
+		timeWeighted: timeWeighted,
+
+		explicitBounds: settings.explicitBounds,
+		bucketCounts:   bucketCounts,
+
+		exponentialHistogram: exponentialHistogram,
 	}
 }
 
-func AggregateGauge[T GaugeValue](aggregate *GaugeAggregate[T], startTS pcommon.Timestamp, value T) {
+func AggregateGauge[T GaugeValue](aggregate *GaugeAggregate[T], startTS pcommon.Timestamp, ts pcommon.Timestamp, value T) {
 	aggregate.count++
 	aggregate.sum += value
+	shifted := float64(value) - aggregate.shift
+	delta := shifted - aggregate.mean
+	aggregate.mean += delta / float64(aggregate.count)
+	aggregate.m2 += delta * (shifted - aggregate.mean)
 	if aggregate.min > value {
 		aggregate.min = value
 	}
@@ -74,20 +191,272 @@ func AggregateGauge[T GaugeValue](aggregate *GaugeAggregate[T], startTS pcommon.
 	if startTS < aggregate.startTS {
 		aggregate.startTS = startTS
 	}
+
+	// On equal timestamps, the order of arrival decides
+	if ts < aggregate.firstTS {
+		aggregate.first = value
+		aggregate.firstTS = ts
+	}
+	if ts >= aggregate.lastTS {
+		aggregate.last = value
+		aggregate.lastTS = ts
+	}
+
+	if aggregate.sketch != nil {
+		aggregate.sketch.Add(float64(value))
+	}
+
+	if aggregate.timeWeighted != nil {
+		aggregate.timeWeighted[0].Add(ts, float64(value))
+	}
+
+	if aggregate.bucketCounts != nil {
+		aggregate.bucketCounts[BucketIndex(aggregate.explicitBounds, float64(value))]++
+	}
+
+	if aggregate.exponentialHistogram != nil {
+		aggregate.exponentialHistogram.Add(float64(value))
+	}
 }
 
-func CreateGaugeMetrics[T GaugeValue](scope pmetric.ScopeMetrics, aggregate *GaugeAggregate[T], aggregationTS pcommon.Timestamp, p *ReduceResolution) {
+// Returns an independent copy of the aggregate with a different set of attributes
+func CopyGaugeAggregate[T GaugeValue](aggregate *GaugeAggregate[T], attributes pcommon.Map) *GaugeAggregate[T] {
+	aggregateCopy := *aggregate
+	aggregateCopy.attributes = attributes
+	if aggregate.sketch != nil {
+		aggregateCopy.sketch = aggregate.sketch.Copy()
+	}
+	if aggregate.timeWeighted != nil {
+		aggregateCopy.timeWeighted = append([]TimeWeightedSeries(nil), aggregate.timeWeighted...)
+	}
+	if aggregate.bucketCounts != nil {
+		aggregateCopy.bucketCounts = append([]uint64(nil), aggregate.bucketCounts...)
+	}
+	if aggregate.exponentialHistogram != nil {
+		aggregateCopy.exponentialHistogram = aggregate.exponentialHistogram.Copy()
+	}
+	return &aggregateCopy
+}
+
+// Merges the samples of another series into the aggregate
+func MergeGaugeAggregate[T GaugeValue](aggregate *GaugeAggregate[T], other *GaugeAggregate[T]) {
+	count := float64(aggregate.count + other.count)
+	delta := (other.shift - aggregate.shift) + (other.mean - aggregate.mean)
+	aggregate.mean += delta * float64(other.count) / count
+	aggregate.m2 += other.m2 + delta*delta*float64(aggregate.count)*float64(other.count)/count
+	aggregate.count += other.count
+	aggregate.sum += other.sum
+	if aggregate.min > other.min {
+		aggregate.min = other.min
+	}
+	if aggregate.max < other.max {
+		aggregate.max = other.max
+	}
+
+	if Abs(aggregate.min_abs) > Abs(other.min_abs) {
+		aggregate.min_abs = other.min_abs
+	}
 
-	createSpecificMetric := func(scope pmetric.ScopeMetrics, aggregate *GaugeAggregate[T], sufix string, value T) {
-		metric := scope.Metrics().AppendEmpty()
-		metric.SetName(aggregate.name + sufix)
-		metric.SetUnit(aggregate.unit)
-		metric.SetDescription(aggregate.description)
-		gauge := metric.SetEmptyGauge()
-		gauge_dp := gauge.DataPoints().AppendEmpty()
-		gauge_dp.SetStartTimestamp(aggregate.startTS)
-		gauge_dp.SetTimestamp(aggregationTS)
-		aggregate.attributes.CopyTo(gauge_dp.Attributes())
+	if Abs(aggregate.max_abs) < Abs(other.max_abs) {
+		aggregate.max_abs = other.max_abs
+	}
+
+	if other.startTS < aggregate.startTS {
+		aggregate.startTS = other.startTS
+	}
+
+	if other.firstTS < aggregate.firstTS {
+		aggregate.first = other.first
+		aggregate.firstTS = other.firstTS
+	}
+	if other.lastTS >= aggregate.lastTS {
+		aggregate.last = other.last
+		aggregate.lastTS = other.lastTS
+	}
+
+	if aggregate.sketch != nil && other.sketch != nil {
+		aggregate.sketch.Merge(other.sketch)
+	}
+
+	if aggregate.timeWeighted != nil && other.timeWeighted != nil {
+		aggregate.timeWeighted = append(aggregate.timeWeighted, other.timeWeighted...)
+	}
+
+	if aggregate.bucketCounts != nil && other.bucketCounts != nil {
+		for i := range other.bucketCounts {
+			aggregate.bucketCounts[i] += other.bucketCounts[i]
+		}
+	}
+
+	if aggregate.exponentialHistogram != nil && other.exponentialHistogram != nil {
+		aggregate.exponentialHistogram.Merge(other.exponentialHistogram)
+	}
+}
+
+// Weights each sample by how long it was in effect, which is until the next sample, or until the end
+// of the window for the last one. The window starts at the end of the previous one, where the last value
+// of the series in that window was still in effect, or else at the first sample. When several series were
+// merged, the result is the mean of the time weighted average of each series
+func TimeWeightedAverage[T GaugeValue](aggregate *GaugeAggregate[T], windowEnd pcommon.Timestamp, scopeKey string, p *ReduceResolution) float64 {
+	p.seriesMutex.Lock()
+	defer p.seriesMutex.Unlock()
+
+	var total float64
+	for _, series := range aggregate.timeWeighted {
+		start := series.firstTS
+		weightedSum := series.weightedSum
+		state := p.seriesState("twa|" + scopeKey + series.key)
+		if state.seen && state.lastTS < series.firstTS {
+			weightedSum += state.doubleValue * float64(series.firstTS-state.lastTS)
+			start = state.lastTS
+		}
+
+		end := windowEnd
+		if end < series.lastTS {
+			end = series.lastTS
+		}
+		weightedSum += series.lastValue * float64(end-series.lastTS)
+
+		if duration := float64(end - start); duration > 0 {
+			total += weightedSum / duration
+		} else {
+			// Every sample has the same timestamp as the end of the window, so only the last one counts
+			total += series.lastValue
+		}
+
+		state.seen = true
+		state.doubleValue = series.lastValue
+		state.lastTS = end
+	}
+	return total / float64(len(aggregate.timeWeighted))
+}
+
+// Emits the whole distribution of the gauge within the window as a single delta histogram
+func CreateGaugeHistogramMetric[T GaugeValue](scope pmetric.ScopeMetrics, aggregate *GaugeAggregate[T], aggregationTS pcommon.Timestamp) {
+	metric := scope.Metrics().AppendEmpty()
+	metric.SetName(aggregate.name)
+	metric.SetUnit(aggregate.unit)
+	metric.SetDescription(aggregate.description)
+	histogram := metric.SetEmptyHistogram()
+	histogram.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	histogram_dp := histogram.DataPoints().AppendEmpty()
+	histogram_dp.SetStartTimestamp(GaugeWindowStart(aggregate))
+	histogram_dp.SetTimestamp(aggregationTS)
+	aggregate.attributes.CopyTo(histogram_dp.Attributes())
+	histogram_dp.SetCount(uint64(aggregate.count))
+	histogram_dp.SetSum(float64(aggregate.sum))
+	histogram_dp.SetMin(float64(aggregate.min))
+	histogram_dp.SetMax(float64(aggregate.max))
+	histogram_dp.ExplicitBounds().FromRaw(aggregate.explicitBounds)
+	histogram_dp.BucketCounts().FromRaw(aggregate.bucketCounts)
+}
+
+// Emits the whole distribution of the gauge within the window as a single delta exponential histogram
+func CreateGaugeExponentialHistogramMetric[T GaugeValue](scope pmetric.ScopeMetrics, aggregate *GaugeAggregate[T], aggregationTS pcommon.Timestamp) {
+	metric := scope.Metrics().AppendEmpty()
+	metric.SetName(aggregate.name)
+	metric.SetUnit(aggregate.unit)
+	metric.SetDescription(aggregate.description)
+	histogram := metric.SetEmptyExponentialHistogram()
+	histogram.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	histogram_dp := histogram.DataPoints().AppendEmpty()
+	histogram_dp.SetStartTimestamp(GaugeWindowStart(aggregate))
+	histogram_dp.SetTimestamp(aggregationTS)
+	aggregate.attributes.CopyTo(histogram_dp.Attributes())
+	histogram_dp.SetCount(uint64(aggregate.count))
+	histogram_dp.SetSum(float64(aggregate.sum))
+	histogram_dp.SetMin(float64(aggregate.min))
+	histogram_dp.SetMax(float64(aggregate.max))
+	aggregate.exponentialHistogram.CopyToDataPoint(histogram_dp)
+}
+
+// Gauges usually have no start timestamp, in which case the window starts with the earliest sample
+func GaugeWindowStart[T GaugeValue](aggregate *GaugeAggregate[T]) pcommon.Timestamp {
+	if aggregate.startTS != 0 {
+		return aggregate.startTS
+	}
+	return aggregate.firstTS
+}
+
+// Emits all the statistics of the gauge as a single summary datapoint. The count and sum of a summary
+// are cumulative, so they are the running totals of the series since it was first seen. The min and
+// max of the window are the quantiles 0 and 1, and the requested percentiles are added in between
+func CreateGaugeSummaryMetric[T GaugeValue](scope pmetric.ScopeMetrics, aggregate *GaugeAggregate[T], aggregationTS pcommon.Timestamp, statistics []Statistic, scopeKey string, p *ReduceResolution) {
+	p.seriesMutex.Lock()
+	state := p.seriesState("summary|" + scopeKey + aggregate.name + "@" + CreateAttributesKey(aggregate.attributes))
+	if !state.seen {
+		state.seen = true
+		state.startTS = GaugeWindowStart(aggregate)
+	}
+	state.count += uint64(aggregate.count)
+	state.sum += float64(aggregate.sum)
+	count, sum, startTS := state.count, state.sum, state.startTS
+	p.seriesMutex.Unlock()
+
+	metric := scope.Metrics().AppendEmpty()
+	metric.SetName(aggregate.name)
+	metric.SetUnit(aggregate.unit)
+	metric.SetDescription(aggregate.description)
+	summary := metric.SetEmptySummary()
+	summary_dp := summary.DataPoints().AppendEmpty()
+	summary_dp.SetStartTimestamp(startTS)
+	summary_dp.SetTimestamp(aggregationTS)
+	aggregate.attributes.CopyTo(summary_dp.Attributes())
+	summary_dp.SetCount(count)
+	summary_dp.SetSum(sum)
+
+	quantiles := []float64{0, 1}
+	for _, statistic := range statistics {
+		if q, ok := statistic.Quantile(); ok && q > 0 && q < 1 && aggregate.sketch != nil {
+			quantiles = append(quantiles, q)
+		}
+	}
+	sort.Float64s(quantiles)
+
+	for i, q := range quantiles {
+		if i > 0 && quantiles[i-1] == q {
+			continue
+		}
+		quantile := summary_dp.QuantileValues().AppendEmpty()
+		quantile.SetQuantile(q)
+		switch q {
+		case 0:
+			quantile.SetValue(float64(aggregate.min))
+		case 1:
+			quantile.SetValue(float64(aggregate.max))
+		default:
+			quantile.SetValue(aggregate.sketch.Quantile(q))
+		}
+	}
+}
+
+func CreateGaugeMetrics[T GaugeValue](scope pmetric.ScopeMetrics, statisticsMetrics StatisticsMetrics, aggregate *GaugeAggregate[T], aggregationTS pcommon.Timestamp, scopeKey string, p *ReduceResolution) {
+	switch p.MetricOptions(aggregate.name).GaugeOutput {
+	case GaugeOutputHistogram:
+		CreateGaugeHistogramMetric(scope, aggregate, aggregationTS)
+		return
+	case GaugeOutputExponentialHistogram:
+		CreateGaugeExponentialHistogramMetric(scope, aggregate, aggregationTS)
+		return
+	case GaugeOutputSummary:
+		CreateGaugeSummaryMetric(scope, aggregate, aggregationTS, p.GaugeStatistics(aggregate.name), scopeKey, p)
+		return
+	}
+
+	emitter := &StatisticsEmitter{
+		p:                   p,
+		scope:               scope,
+		statisticsMetrics:   statisticsMetrics,
+		name:                aggregate.name,
+		description:         aggregate.description,
+		unit:                aggregate.unit,
+		attributes:          aggregate.attributes,
+		startTS:             aggregate.startTS,
+		defaultTemplate:     DefaultGaugeNameTemplate,
+		attributeMetricName: aggregate.name,
+	}
+	createTimedMetric := func(statistic string, value T, timestamp pcommon.Timestamp) {
+		gauge_dp := emitter.DataPoint(statistic, aggregate.unit, timestamp)
 		switch v := any(value).(type) {
 		case int64:
 			gauge_dp.SetIntValue(v)
@@ -95,45 +464,53 @@ func CreateGaugeMetrics[T GaugeValue](scope pmetric.ScopeMetrics, aggregate *Gau
 			gauge_dp.SetDoubleValue(v)
 		}
 	}
+	createSpecificMetric := func(statistic string, value T) {
+		createTimedMetric(statistic, value, aggregationTS)
+	}
+	createFloatMetric := func(statistic string, value float64) {
+		emitter.DataPoint(statistic, aggregate.unit, aggregationTS).SetDoubleValue(value)
+	}
 	aggregate.average = aggregate.sum / T(aggregate.count)
 
 	// The average is commented in order to reduce the number of metrics generated
 	// from one gauge, however this show an example on the gauge can be broken down
 	// into more or less metrics depending on what is required
-	//  createSpecificMetric(scope, aggregate, "_gauge_avg", aggregate.average)
-
-	if statistics, ok := p.Config.MetricsStatistics[strings.ToLower(aggregate.name)]; ok {
-		for _, statistic := range statistics {
-			switch statistic {
-			case "avg":
-				createSpecificMetric(scope, aggregate, "_gauge_avg", aggregate.average)
-			case "sum":
-				createSpecificMetric(scope, aggregate, "_gauge_sum", aggregate.sum)
-			case "min":
-				createSpecificMetric(scope, aggregate, "_gauge_min", aggregate.min)
-			case "max":
-				createSpecificMetric(scope, aggregate, "_gauge_max", aggregate.max)
-			case "abs_min":
-				createSpecificMetric(scope, aggregate, "_gauge_abs_min", aggregate.min_abs)
-			case "abs_max":
-				createSpecificMetric(scope, aggregate, "_gauge_abs_max", aggregate.max_abs)
-			case "count":
-				metric := scope.Metrics().AppendEmpty()
-				metric.SetName(aggregate.name + "_gauge_count")
-				metric.SetDescription(aggregate.description)
-				gauge := metric.SetEmptyGauge()
-				gauge_dp := gauge.DataPoints().AppendEmpty()
-				gauge_dp.SetStartTimestamp(aggregate.startTS)
-				gauge_dp.SetTimestamp(aggregationTS)
-				aggregate.attributes.CopyTo(gauge_dp.Attributes())
-				gauge_dp.SetIntValue(aggregate.count)
-			default:
-				p.Logger.Warn("Type " + statistic + " is not valid. Tried for metric " + aggregate.name)
+	//  createSpecificMetric("avg", aggregate.average)
+
+	for _, statistic := range p.GaugeStatistics(aggregate.name) {
+		switch statistic {
+		case StatisticAvg:
+			createSpecificMetric("avg", aggregate.average)
+		case StatisticSum:
+			createSpecificMetric("sum", aggregate.sum)
+		case StatisticMin:
+			createSpecificMetric("min", aggregate.min)
+		case StatisticMax:
+			createSpecificMetric("max", aggregate.max)
+		case StatisticAbsMin:
+			createSpecificMetric("abs_min", aggregate.min_abs)
+		case StatisticAbsMax:
+			createSpecificMetric("abs_max", aggregate.max_abs)
+		case StatisticFirst:
+			createTimedMetric("first", aggregate.first, aggregate.firstTS)
+		case StatisticLast:
+			createTimedMetric("last", aggregate.last, aggregate.lastTS)
+		case StatisticTWA:
+			createFloatMetric("twa", TimeWeightedAverage(aggregate, aggregationTS, scopeKey, p))
+		case StatisticRange:
+			createSpecificMetric("range", aggregate.max-aggregate.min)
+		case StatisticVariance:
+			createFloatMetric("variance", aggregate.m2/float64(aggregate.count))
+		case StatisticStddev:
+			createFloatMetric("stddev", math.Sqrt(aggregate.m2/float64(aggregate.count)))
+		case StatisticCount:
+			// The count has no unit
+			emitter.DataPoint("count", "", aggregationTS).SetIntValue(aggregate.count)
+		default:
+			// Any other statistic is a percentile, the config is validated when it is loaded
+			if q, ok := statistic.Quantile(); ok && aggregate.sketch != nil {
+				createFloatMetric(statistic.Name(), aggregate.sketch.Quantile(q))
 			}
 		}
-	} else {
-		// default to abs min max
-		createSpecificMetric(scope, aggregate, "_gauge_abs_max", aggregate.max_abs)
-		createSpecificMetric(scope, aggregate, "_gauge_abs_min", aggregate.min_abs)
 	}
 }
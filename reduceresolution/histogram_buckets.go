@@ -0,0 +1,197 @@
+// Copyright (C) 2025 Bang & Olufsen A/S, Denmark
+//
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package reduceresolution
+
+import (
+	"math"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// MergedBounds returns the bounds that two histograms with different bounds are re-bucketed onto,
+// or false when the mismatch strategy drops the datapoint
+func MergedBounds(mismatch string, configured []float64, a []float64, b []float64) ([]float64, bool) {
+	switch mismatch {
+	case HistogramBoundsMismatchCommon:
+		bounds := []float64{}
+		for i, j := 0, 0; i < len(a) && j < len(b); {
+			switch {
+			case a[i] < b[j]:
+				i++
+			case a[i] > b[j]:
+				j++
+			default:
+				bounds = append(bounds, a[i])
+				i++
+				j++
+			}
+		}
+		return bounds, true
+	case HistogramBoundsMismatchUnion:
+		bounds := make([]float64, 0, len(a)+len(b))
+		i, j := 0, 0
+		for i < len(a) || j < len(b) {
+			switch {
+			case j == len(b) || (i < len(a) && a[i] < b[j]):
+				bounds = append(bounds, a[i])
+				i++
+			case i == len(a) || b[j] < a[i]:
+				bounds = append(bounds, b[j])
+				j++
+			default:
+				bounds = append(bounds, a[i])
+				i++
+				j++
+			}
+		}
+		return bounds, true
+	case HistogramBoundsMismatchConfigured:
+		if len(configured) == 0 {
+			return nil, false
+		}
+		return append([]float64(nil), configured...), true
+	}
+	return nil, false
+}
+
+// KnownMinMax returns the minimum and maximum of a histogram, or the infinities when they are not set,
+// so that they do not narrow any bucket
+func KnownMinMax(min float64, hasMin bool, max float64, hasMax bool) (float64, float64) {
+	if !hasMin {
+		min = math.Inf(-1)
+	}
+	if !hasMax {
+		max = math.Inf(1)
+	}
+	return min, max
+}
+
+// RebucketHistogram moves the bucket counts onto other bounds. A bucket that overlaps several of the new
+// buckets is split in proportion to the overlap, assuming its observations are spread evenly between its
+// bounds. The minimum and maximum narrow the first and last buckets, which are unbounded otherwise, and
+// are infinite when they are unknown
+func RebucketHistogram(bucketCounts []uint64, explicitBounds []float64, min float64, max float64, bounds []float64) []uint64 {
+	rebucketed := make([]uint64, len(bounds)+1)
+	for i, count := range bucketCounts {
+		if count == 0 {
+			continue
+		}
+		lower, upper := math.Inf(-1), math.Inf(1)
+		if i > 0 {
+			lower = explicitBounds[i-1]
+		}
+		if i < len(explicitBounds) {
+			upper = explicitBounds[i]
+		}
+		if min > lower && min < upper {
+			lower = min
+		}
+		if max < upper && max > lower {
+			upper = max
+		}
+
+		// Without a width the observations cannot be spread, so they all go to the bucket of a single value.
+		// The observations of an unbounded last bucket are above its lower bound, so they go to the first
+		// bucket that holds values above it
+		if math.IsInf(lower, 0) || math.IsInf(upper, 0) || lower >= upper {
+			switch {
+			case math.IsInf(upper, 1) && !math.IsInf(lower, -1):
+				rebucketed[sort.Search(len(bounds), func(j int) bool { return bounds[j] > lower })] += count
+			case math.IsInf(upper, 1):
+				rebucketed[BucketIndex(bounds, max)] += count
+			default:
+				rebucketed[BucketIndex(bounds, upper)] += count
+			}
+			continue
+		}
+
+		// The running total is rounded, so the split counts always add up to the count of the bucket
+		var assigned uint64
+		var fraction float64
+		for j := BucketIndex(bounds, lower); j <= len(bounds); j++ {
+			bucketLower, bucketUpper := lower, upper
+			if j > 0 && bounds[j-1] > bucketLower {
+				bucketLower = bounds[j-1]
+			}
+			if j < len(bounds) && bounds[j] < bucketUpper {
+				bucketUpper = bounds[j]
+			}
+			if bucketUpper > bucketLower {
+				fraction += (bucketUpper - bucketLower) / (upper - lower)
+			}
+			total := uint64(math.Round(fraction * float64(count)))
+			if total > count || j == len(bounds) || bounds[j] >= upper {
+				total = count
+			}
+			rebucketed[j] += total - assigned
+			assigned = total
+			if assigned == count {
+				break
+			}
+		}
+	}
+	return rebucketed
+}
+
+// Moves the buckets of the aggregate onto other bounds
+func (aggregate *HistogramAggregate) rebucket(bounds []float64) {
+	min, max := KnownMinMax(aggregate.min, aggregate.hasMin, aggregate.max, aggregate.hasMax)
+	aggregate.bucketCounts = RebucketHistogram(aggregate.bucketCounts, aggregate.explicitBounds, min, max, bounds)
+	aggregate.explicitBounds = bounds
+}
+
+// Returns the bounds both the aggregate and the other buckets are moved onto when their bounds differ,
+// or false when they cannot be merged
+func (aggregate *HistogramAggregate) mergedBounds(explicitBounds []float64, bucketCounts int) ([]float64, bool) {
+	if len(explicitBounds)+1 != bucketCounts || len(aggregate.explicitBounds)+1 != len(aggregate.bucketCounts) {
+		return nil, false
+	}
+	return MergedBounds(aggregate.boundsMismatch, aggregate.configuredBounds, aggregate.explicitBounds, explicitBounds)
+}
+
+// CoarsenBuckets returns the buckets the histogram is emitted with, which are coarser than the aggregated ones
+// when the metric has coarse-bounds or merge-buckets. Buckets are only ever merged, never split, so the counts
+// of a cumulative histogram keep growing from one window to the next. A coarse bound that was not received is
+// dropped with a warning, and when none were received the histogram is emitted with a single bucket.
+// The aggregate itself is left untouched
+func CoarsenBuckets(aggregate *HistogramAggregate, p *ReduceResolution) ([]uint64, []float64) {
+	options := p.MetricOptions(aggregate.name)
+	if len(aggregate.explicitBounds)+1 != len(aggregate.bucketCounts) {
+		return aggregate.bucketCounts, aggregate.explicitBounds
+	}
+	var bounds []float64
+	switch {
+	case len(options.CoarseBounds) > 0:
+		// A coarse bound that was not received would split a bucket, so only the ones in common are kept
+		bounds, _ = MergedBounds(HistogramBoundsMismatchCommon, nil, aggregate.explicitBounds, options.CoarseBounds)
+		if len(bounds) < len(options.CoarseBounds) {
+			p.Logger.Warn("Coarse bounds dropped as they were not received", zap.String("metric", aggregate.name),
+				zap.Float64s("coarse-bounds", options.CoarseBounds), zap.Float64s("bounds", bounds))
+		}
+	case options.MergeBuckets > 1:
+		// Every group of adjacent buckets ends at the upper bound of its last bucket
+		bounds = make([]float64, 0, len(aggregate.explicitBounds)/options.MergeBuckets)
+		for i := options.MergeBuckets - 1; i < len(aggregate.explicitBounds); i += options.MergeBuckets {
+			bounds = append(bounds, aggregate.explicitBounds[i])
+		}
+	default:
+		return aggregate.bucketCounts, aggregate.explicitBounds
+	}
+	if CompareFloat64SlicesEqual(aggregate.explicitBounds, bounds) {
+		return aggregate.bucketCounts, aggregate.explicitBounds
+	}
+
+	// The bounds are a subset of the received ones, so every bucket fits in the coarse bucket of its upper bound
+	bucketCounts := make([]uint64, len(bounds)+1)
+	for i, count := range aggregate.bucketCounts {
+		index := len(bounds)
+		if i < len(aggregate.explicitBounds) {
+			index = BucketIndex(bounds, aggregate.explicitBounds[i])
+		}
+		bucketCounts[index] += count
+	}
+	return bucketCounts, bounds
+}
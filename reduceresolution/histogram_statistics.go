@@ -5,6 +5,9 @@
 package reduceresolution
 
 import (
+	"math"
+	"sort"
+
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 )
@@ -21,11 +24,15 @@ func CompareFloat64SlicesEqual(a, b []float64) bool {
 	return true
 }
 
+// Index of the bucket a value falls into, where each bucket includes its upper bound
+func BucketIndex(explicitBounds []float64, value float64) int {
+	return sort.SearchFloat64s(explicitBounds, value)
+}
+
 type HistogramAggregate struct {
+	MinMax
 	count          uint64
 	sum            float64
-	max            float64
-	min            float64
 	bucketCounts   []uint64
 	explicitBounds []float64
 	name           string
@@ -35,23 +42,34 @@ type HistogramAggregate struct {
 	startTS        pcommon.Timestamp
 	lastTS         pcommon.Timestamp
 	aggregation    pmetric.AggregationTemporality
+
+	// Only used for cumulative histograms, to detect when the series was reset
+	CumulativeSeries[HistogramAggregate]
+
+	// How datapoints with different bounds are merged, see histogram-bounds-mismatch
+	boundsMismatch   string
+	configuredBounds []float64
 }
 
-func CreateHistogramAggregate(metric pmetric.Metric, value pmetric.HistogramDataPoint) *HistogramAggregate {
+func CreateHistogramAggregate(metric pmetric.Metric, value pmetric.HistogramDataPoint, resets string, boundsMismatch string, configuredBounds []float64) *HistogramAggregate {
 	return &HistogramAggregate{
 		count:          value.Count(),
 		sum:            value.Sum(),
-		max:            value.Max(),
-		min:            value.Min(),
+		MinMax:         DataPointMinMax(value),
 		bucketCounts:   value.BucketCounts().AsRaw(),
 		explicitBounds: value.ExplicitBounds().AsRaw(),
 		name:           metric.Name(),
 		unit:           metric.Unit(),
 		description:    metric.Description(),
-		attributes:     value.Attributes(),
+		attributes:     CopyAttributes(value.Attributes()),
 		startTS:        value.StartTimestamp(),
 		lastTS:         value.Timestamp(),
 		aggregation:    metric.Histogram().AggregationTemporality(),
+
+		CumulativeSeries: CreateCumulativeSeries[HistogramAggregate](resets, value.StartTimestamp()),
+
+		boundsMismatch:   boundsMismatch,
+		configuredBounds: configuredBounds,
 	}
 }
 
@@ -59,14 +77,38 @@ func AggregateHistogram(aggregate *HistogramAggregate, value pmetric.HistogramDa
 	switch aggregate.aggregation {
 	case pmetric.AggregationTemporalityCumulative:
 		if aggregate.lastTS < value.Timestamp() {
+			reset := aggregate.IsReset(value, aggregate.count)
+			bounds := value.ExplicitBounds().AsRaw()
+			if aggregate.Accumulates(reset) && (!CompareFloat64SlicesEqual(aggregate.explicitBounds, bounds) ||
+				len(aggregate.bucketCounts) != value.BucketCounts().Len()) {
+				var ok bool
+				if bounds, ok = aggregate.mergedBounds(bounds, value.BucketCounts().Len()); !ok {
+					return 1
+				}
+			}
+			aggregate.Continue(value, reset, &aggregate.startTS, aggregate.lastTS, aggregate.count, func() *HistogramAggregate {
+				offset := CopyHistogramAggregate(aggregate, aggregate.attributes)
+				offset.offset = nil
+				return offset
+			})
 			aggregate.count = value.Count()
 			aggregate.sum = value.Sum()
-			aggregate.max = value.Max()
-			aggregate.min = value.Min()
+			aggregate.MinMax = DataPointMinMax(value)
 			aggregate.bucketCounts = value.BucketCounts().AsRaw()
 			aggregate.explicitBounds = value.ExplicitBounds().AsRaw()
-			if value.StartTimestamp() < aggregate.startTS {
-				aggregate.startTS = value.StartTimestamp()
+			if !CompareFloat64SlicesEqual(aggregate.explicitBounds, bounds) {
+				aggregate.rebucket(bounds)
+			}
+			if aggregate.offset != nil {
+				if !CompareFloat64SlicesEqual(aggregate.offset.explicitBounds, bounds) {
+					aggregate.offset.rebucket(bounds)
+				}
+				for i := range aggregate.offset.bucketCounts {
+					aggregate.bucketCounts[i] += aggregate.offset.bucketCounts[i]
+				}
+				aggregate.count += aggregate.offset.count
+				aggregate.sum += aggregate.offset.sum
+				aggregate.MergeMinMax(aggregate.offset.MinMax)
 			}
 			aggregate.lastTS = value.Timestamp()
 		}
@@ -77,17 +119,22 @@ func AggregateHistogram(aggregate *HistogramAggregate, value pmetric.HistogramDa
 				aggregate.bucketCounts[i] = aggregate.bucketCounts[i] + value.BucketCounts().At(i)
 			}
 		} else {
-			return 1
+			bounds, ok := aggregate.mergedBounds(value.ExplicitBounds().AsRaw(), value.BucketCounts().Len())
+			if !ok {
+				return 1
+			}
+			if !CompareFloat64SlicesEqual(aggregate.explicitBounds, bounds) {
+				aggregate.rebucket(bounds)
+			}
+			min, max := KnownMinMax(value.Min(), value.HasMin(), value.Max(), value.HasMax())
+			bucketCounts := RebucketHistogram(value.BucketCounts().AsRaw(), value.ExplicitBounds().AsRaw(), min, max, bounds)
+			for i := range bucketCounts {
+				aggregate.bucketCounts[i] += bucketCounts[i]
+			}
 		}
 		aggregate.count += value.Count()
 		aggregate.sum += value.Sum()
-		if aggregate.max < value.Max() {
-			aggregate.max = value.Max()
-		}
-		if aggregate.min > value.Min() {
-			aggregate.min = value.Min()
-		}
-		aggregate.explicitBounds = value.ExplicitBounds().AsRaw()
+		aggregate.MergeMinMax(DataPointMinMax(value))
 		if value.StartTimestamp() < aggregate.startTS {
 			aggregate.startTS = value.StartTimestamp()
 		}
@@ -95,7 +142,47 @@ func AggregateHistogram(aggregate *HistogramAggregate, value pmetric.HistogramDa
 	return 0
 }
 
-func CreateHistogramMetrics(scope pmetric.ScopeMetrics, aggregate *HistogramAggregate, aggregationTS pcommon.Timestamp) {
+// Returns an independent copy of the aggregate with a different set of attributes
+func CopyHistogramAggregate(aggregate *HistogramAggregate, attributes pcommon.Map) *HistogramAggregate {
+	aggregateCopy := *aggregate
+	aggregateCopy.bucketCounts = append([]uint64(nil), aggregate.bucketCounts...)
+	aggregateCopy.explicitBounds = append([]float64(nil), aggregate.explicitBounds...)
+	aggregateCopy.attributes = attributes
+	return &aggregateCopy
+}
+
+// Merges another series into the aggregate. Just like AggregateHistogram, it returns 1 when the buckets do not match
+// and cannot be re-bucketed
+func MergeHistogramAggregate(aggregate *HistogramAggregate, other *HistogramAggregate) int16 {
+	bucketCounts := other.bucketCounts
+	if !CompareFloat64SlicesEqual(aggregate.explicitBounds, other.explicitBounds) ||
+		len(aggregate.bucketCounts) != len(other.bucketCounts) {
+		bounds, ok := aggregate.mergedBounds(other.explicitBounds, len(other.bucketCounts))
+		if !ok {
+			return 1
+		}
+		if !CompareFloat64SlicesEqual(aggregate.explicitBounds, bounds) {
+			aggregate.rebucket(bounds)
+		}
+		min, max := KnownMinMax(other.min, other.hasMin, other.max, other.hasMax)
+		bucketCounts = RebucketHistogram(other.bucketCounts, other.explicitBounds, min, max, bounds)
+	}
+	for i := range bucketCounts {
+		aggregate.bucketCounts[i] += bucketCounts[i]
+	}
+	aggregate.count += other.count
+	aggregate.sum += other.sum
+	aggregate.MergeMinMax(other.MinMax)
+	if other.startTS < aggregate.startTS {
+		aggregate.startTS = other.startTS
+	}
+	if aggregate.lastTS < other.lastTS {
+		aggregate.lastTS = other.lastTS
+	}
+	return 0
+}
+
+func CreateHistogramMetrics(scope pmetric.ScopeMetrics, aggregate *HistogramAggregate, aggregationTS pcommon.Timestamp, p *ReduceResolution) {
 	metric_value := scope.Metrics().AppendEmpty()
 	metric_value.SetName(aggregate.name)
 	metric_value.SetUnit(aggregate.unit)
@@ -108,14 +195,158 @@ func CreateHistogramMetrics(scope pmetric.ScopeMetrics, aggregate *HistogramAggr
 	aggregate.attributes.CopyTo(histogram_dp.Attributes())
 	histogram_dp.SetCount(aggregate.count)
 	histogram_dp.SetSum(aggregate.sum)
-	histogram_dp.SetMax(aggregate.max)
-	histogram_dp.SetMin(aggregate.min)
+	if aggregate.hasMin {
+		histogram_dp.SetMin(aggregate.min)
+	}
+	if aggregate.hasMax {
+		histogram_dp.SetMax(aggregate.max)
+	}
 
-	for i := 0; i < len(aggregate.explicitBounds); i++ {
-		histogram_dp.ExplicitBounds().Append(aggregate.explicitBounds[i])
+	bucketCounts, explicitBounds := CoarsenBuckets(aggregate, p)
+	for i := 0; i < len(explicitBounds); i++ {
+		histogram_dp.ExplicitBounds().Append(explicitBounds[i])
 	}
 
-	for i := 0; i < len(aggregate.bucketCounts); i++ {
-		histogram_dp.BucketCounts().Append(aggregate.bucketCounts[i])
+	for i := 0; i < len(bucketCounts); i++ {
+		histogram_dp.BucketCounts().Append(bucketCounts[i])
+	}
+}
+
+// Estimates the quantile by linear interpolation within the bucket that holds it, as if its observations
+// were spread evenly between its bounds. The minimum and maximum bound the first and last buckets, and the result.
+// Without them, like for histograms converted from Prometheus, an unbounded bucket gives its finite bound
+func HistogramQuantile(aggregate *HistogramAggregate, q float64) float64 {
+	min, max := KnownMinMax(aggregate.min, aggregate.hasMin, aggregate.max, aggregate.hasMax)
+	rank := q * float64(aggregate.count)
+	if rank <= 0 && aggregate.hasMin {
+		return aggregate.min
+	}
+	var cumulative uint64
+	for i, count := range aggregate.bucketCounts {
+		if count == 0 || float64(cumulative+count) < rank {
+			cumulative += count
+			continue
+		}
+		lower, upper := min, max
+		if i > 0 && i <= len(aggregate.explicitBounds) && aggregate.explicitBounds[i-1] > lower {
+			lower = aggregate.explicitBounds[i-1]
+		}
+		if i < len(aggregate.explicitBounds) && aggregate.explicitBounds[i] < upper {
+			upper = aggregate.explicitBounds[i]
+		}
+		switch {
+		case math.IsInf(lower, -1) && math.IsInf(upper, 1):
+			// Without any bound, the mean is the only estimate there is
+			return aggregate.sum / float64(aggregate.count)
+		case math.IsInf(lower, -1):
+			return upper
+		case math.IsInf(upper, 1):
+			return lower
+		}
+		value := lower + (upper-lower)*(rank-float64(cumulative))/float64(count)
+		return math.Max(min, math.Min(max, value))
+	}
+	switch {
+	case aggregate.hasMax:
+		return aggregate.max
+	case len(aggregate.explicitBounds) > 0:
+		return aggregate.explicitBounds[len(aggregate.explicitBounds)-1]
+	}
+	return aggregate.sum / float64(aggregate.count)
+}
+
+// BucketCountsIncrease returns the increase of every bucket since the previous counts, or false when
+// any bucket decreased, which means the series was reset
+func BucketCountsIncrease(bucketCounts []uint64, previous []uint64) ([]uint64, bool) {
+	if len(bucketCounts) != len(previous) {
+		return nil, false
+	}
+	increase := make([]uint64, len(bucketCounts))
+	for i := range bucketCounts {
+		if bucketCounts[i] < previous[i] {
+			return nil, false
+		}
+		increase[i] = bucketCounts[i] - previous[i]
+	}
+	return increase, true
+}
+
+// HistogramWindowDelta returns the observations of the histogram within the window. A cumulative histogram is
+// compared with its buckets in the previous window, unless this is its first window, it was reset or its bounds
+// changed, then it holds everything since the start of the series. The second result tells if the minimum and
+// maximum are the ones of the window, otherwise they are the ones of the whole series and only bound the window
+func HistogramWindowDelta(aggregate *HistogramAggregate, seriesKey string, p *ReduceResolution) (*HistogramAggregate, bool) {
+	if aggregate.aggregation != pmetric.AggregationTemporalityCumulative {
+		return aggregate, true
+	}
+	p.seriesMutex.Lock()
+	defer p.seriesMutex.Unlock()
+	state := p.seriesState("histogram-statistics|" + seriesKey)
+	delta, windowMinMax := aggregate, true
+	if state.seen && state.startTS == aggregate.startTS && aggregate.count >= state.count &&
+		CompareFloat64SlicesEqual(state.explicitBounds, aggregate.explicitBounds) {
+		if bucketCounts, ok := BucketCountsIncrease(aggregate.bucketCounts, state.bucketCounts); ok {
+			delta = CopyHistogramAggregate(aggregate, aggregate.attributes)
+			delta.count -= state.count
+			delta.sum -= state.sum
+			delta.bucketCounts = bucketCounts
+			windowMinMax = false
+		}
+	}
+	state.seen = true
+	state.startTS = aggregate.startTS
+	state.count = aggregate.count
+	state.sum = aggregate.sum
+	state.bucketCounts = append([]uint64(nil), aggregate.bucketCounts...)
+	state.explicitBounds = append([]float64(nil), aggregate.explicitBounds...)
+	state.lastTS = aggregate.lastTS
+	return delta, windowMinMax
+}
+
+// Emits the statistics configured for a histogram as gauges, next to the histogram itself unless it is dropped.
+// The statistics of a cumulative histogram are the ones of the observations within the window
+func CreateHistogramStatisticsMetrics(scope pmetric.ScopeMetrics, statisticsMetrics StatisticsMetrics, aggregate *HistogramAggregate, aggregationTS pcommon.Timestamp, seriesKey string, p *ReduceResolution) {
+	statistics, ok := p.HistogramStatistics(aggregate.name)
+	if !ok {
+		return
+	}
+	delta, windowMinMax := HistogramWindowDelta(aggregate, seriesKey, p)
+
+	emitter := &StatisticsEmitter{
+		p:                   p,
+		scope:               scope,
+		statisticsMetrics:   statisticsMetrics,
+		name:                aggregate.name,
+		description:         aggregate.description,
+		unit:                aggregate.unit,
+		attributes:          aggregate.attributes,
+		startTS:             aggregate.startTS,
+		defaultTemplate:     DefaultNameTemplate,
+		attributeMetricName: aggregate.name + "_" + p.Config.StatisticAttribute,
+	}
+
+	for _, statistic := range statistics {
+		switch statistic {
+		case StatisticCount:
+			emitter.DataPoint("count", "", aggregationTS).SetIntValue(int64(delta.count))
+		case StatisticSum:
+			emitter.DataPoint("sum", aggregate.unit, aggregationTS).SetDoubleValue(delta.sum)
+		case StatisticMean, StatisticMin, StatisticMax:
+			if delta.count == 0 || (statistic != StatisticMean && !windowMinMax) ||
+				(statistic == StatisticMin && !delta.hasMin) || (statistic == StatisticMax && !delta.hasMax) {
+				continue
+			}
+			value := delta.sum / float64(delta.count)
+			if statistic == StatisticMin {
+				value = delta.min
+			} else if statistic == StatisticMax {
+				value = delta.max
+			}
+			emitter.DataPoint(string(statistic), aggregate.unit, aggregationTS).SetDoubleValue(value)
+		default:
+			if q, ok := statistic.Quantile(); ok && delta.count > 0 {
+				emitter.DataPoint(statistic.Name(), aggregate.unit, aggregationTS).SetDoubleValue(HistogramQuantile(delta, q))
+			}
+		}
 	}
 }
@@ -0,0 +1,190 @@
+// Copyright (C) 2025 Bang & Olufsen A/S, Denmark
+//
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package reduceresolution
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Upper limit of buckets for each sign, the buckets closest to zero are collapsed beyond it
+	QuantileSketchMaxBuckets = 2048
+	// Values with a smaller magnitude are counted as zero
+	QuantileSketchMinValue = 1e-9
+)
+
+// QuantileSketch is a DDSketch, which keeps the values in logarithmically sized buckets.
+// Any quantile is estimated with a relative error bounded by the configured accuracy, and
+// two sketches with the same accuracy can be merged without losing precision
+type QuantileSketch struct {
+	relativeAccuracy float64
+	gamma            float64
+	logGamma         float64
+	positive         map[int]uint64
+	negative         map[int]uint64
+	zeroCount        uint64
+	count            uint64
+	min              float64
+	max              float64
+}
+
+func CreateQuantileSketch(relativeAccuracy float64) *QuantileSketch {
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &QuantileSketch{
+		relativeAccuracy: relativeAccuracy,
+		gamma:            gamma,
+		logGamma:         math.Log(gamma),
+		positive:         make(map[int]uint64),
+		negative:         make(map[int]uint64),
+		min:              math.Inf(1),
+		max:              math.Inf(-1),
+	}
+}
+
+func (sketch *QuantileSketch) index(value float64) int {
+	return int(math.Ceil(math.Log(value) / sketch.logGamma))
+}
+
+func (sketch *QuantileSketch) value(index int) float64 {
+	return 2 * math.Pow(sketch.gamma, float64(index)) / (sketch.gamma + 1)
+}
+
+func (sketch *QuantileSketch) Add(value float64) {
+	switch {
+	case value > QuantileSketchMinValue:
+		sketch.positive[sketch.index(value)]++
+		collapseLowestBuckets(sketch.positive)
+	case value < -QuantileSketchMinValue:
+		sketch.negative[sketch.index(-value)]++
+		collapseLowestBuckets(sketch.negative)
+	default:
+		sketch.zeroCount++
+	}
+	sketch.count++
+	sketch.min = math.Min(sketch.min, value)
+	sketch.max = math.Max(sketch.max, value)
+}
+
+// Merges another sketch into this one, both sketches must have the same accuracy
+func (sketch *QuantileSketch) Merge(other *QuantileSketch) {
+	for index, count := range other.positive {
+		sketch.positive[index] += count
+	}
+	for index, count := range other.negative {
+		sketch.negative[index] += count
+	}
+	collapseLowestBuckets(sketch.positive)
+	collapseLowestBuckets(sketch.negative)
+	sketch.zeroCount += other.zeroCount
+	sketch.count += other.count
+	sketch.min = math.Min(sketch.min, other.min)
+	sketch.max = math.Max(sketch.max, other.max)
+}
+
+func (sketch *QuantileSketch) Copy() *QuantileSketch {
+	sketchCopy := *sketch
+	sketchCopy.positive = make(map[int]uint64, len(sketch.positive))
+	for index, count := range sketch.positive {
+		sketchCopy.positive[index] = count
+	}
+	sketchCopy.negative = make(map[int]uint64, len(sketch.negative))
+	for index, count := range sketch.negative {
+		sketchCopy.negative[index] = count
+	}
+	return &sketchCopy
+}
+
+// Quantile estimates the value at the quantile q, which must be between 0 and 1
+func (sketch *QuantileSketch) Quantile(q float64) float64 {
+	if sketch.count == 0 {
+		return math.NaN()
+	}
+	rank := uint64(q * float64(sketch.count-1))
+
+	var estimate float64
+	var seen uint64
+	found := false
+
+	// From the most negative value to the most positive one
+	negativeIndexes := sortedIndexes(sketch.negative)
+	for i := len(negativeIndexes) - 1; i >= 0 && !found; i-- {
+		seen += sketch.negative[negativeIndexes[i]]
+		if seen > rank {
+			estimate = -sketch.value(negativeIndexes[i])
+			found = true
+		}
+	}
+	if !found {
+		seen += sketch.zeroCount
+		if seen > rank {
+			estimate = 0
+			found = true
+		}
+	}
+	for _, index := range sortedIndexes(sketch.positive) {
+		if found {
+			break
+		}
+		seen += sketch.positive[index]
+		if seen > rank {
+			estimate = sketch.value(index)
+			found = true
+		}
+	}
+
+	return math.Max(sketch.min, math.Min(sketch.max, estimate))
+}
+
+func sortedIndexes(buckets map[int]uint64) []int {
+	indexes := make([]int, 0, len(buckets))
+	for index := range buckets {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// Keeps the number of buckets bounded by merging the ones closest to zero, so the accuracy is
+// only lost for the lowest quantiles of the magnitude
+func collapseLowestBuckets(buckets map[int]uint64) {
+	if len(buckets) <= QuantileSketchMaxBuckets {
+		return
+	}
+	indexes := sortedIndexes(buckets)
+	excess := len(indexes) - QuantileSketchMaxBuckets
+	target := indexes[excess]
+	for _, index := range indexes[:excess] {
+		buckets[target] += buckets[index]
+		delete(buckets, index)
+	}
+}
+
+// ParseQuantile returns the quantile of a percentile statistic, like p95, p99.9 or quantile(0.999)
+func ParseQuantile(statistic string) (float64, bool) {
+	if strings.HasPrefix(statistic, "quantile(") && strings.HasSuffix(statistic, ")") {
+		q, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimPrefix(statistic, "quantile("), ")"), 64)
+		if err != nil || !(q >= 0 && q <= 1) {
+			return 0, false
+		}
+		return q, true
+	}
+	if strings.HasPrefix(statistic, "p") {
+		percentile, err := strconv.ParseFloat(strings.TrimPrefix(statistic, "p"), 64)
+		if err != nil || !(percentile >= 0 && percentile <= 100) {
+			return 0, false
+		}
+		return percentile / 100, true
+	}
+	return 0, false
+}
+
+// Name of the quantile as a percentile that can be used in a metric name, for example p99_9
+func QuantileName(q float64) string {
+	percentile := math.Round(q*100*1e6) / 1e6
+	return "p" + strings.ReplaceAll(strconv.FormatFloat(percentile, 'f', -1, 64), ".", "_")
+}
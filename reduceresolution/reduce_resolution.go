@@ -6,36 +6,192 @@ package reduceresolution
 
 import (
 	"context"
+	"strings"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/processor/processorhelper"
 	"go.uber.org/zap"
 )
 
 type ReduceResolution struct {
 	Logger *zap.Logger
 	Config ProcessedConfig
+
+	// Only used when an interval is configured
+	nextConsumer consumer.Metrics
+	mutex        sync.Mutex
+	window       map[string]*ResourceContainer
+	done         chan struct{}
+	wg           sync.WaitGroup
+
+	// State of the series that is kept across windows, like for converting the temporality
+	seriesMutex sync.Mutex
+	series      map[string]*SeriesState
+	// Number of windows that were emitted, to tell how long ago a series was seen
+	windowCount uint64
+
+	// Statistics of each gauge by name, so the aggregation rules are only matched once per gauge
+	gaugeStatisticsMutex sync.Mutex
+	gaugeStatistics      map[string][]Statistic
+}
+
+// MetricOptions returns the options configured for a metric, or the default ones if there are none
+func (p *ReduceResolution) MetricOptions(name string) MetricOptions {
+	return p.Config.MetricsOptions[strings.ToLower(name)]
+}
+
+// CumulativeResets returns what is done when a cumulative series of the metric is reset
+func (p *ReduceResolution) CumulativeResets(name string) string {
+	if resets := p.MetricOptions(name).CumulativeResets; resets != "" {
+		return resets
+	}
+	return p.Config.CumulativeResets
+}
+
+// HistogramBoundsMismatch returns what is done with a histogram datapoint of the metric whose bounds differ
+func (p *ReduceResolution) HistogramBoundsMismatch(name string) string {
+	if mismatch := p.MetricOptions(name).HistogramBoundsMismatch; mismatch != "" {
+		return mismatch
+	}
+	return p.Config.HistogramBoundsMismatch
+}
+
+// SummaryQuantiles returns which value of each quantile is emitted for a summary of the metric
+func (p *ReduceResolution) SummaryQuantiles(name string) string {
+	if quantiles := p.MetricOptions(name).SummaryQuantiles; quantiles != "" {
+		return quantiles
+	}
+	return p.Config.SummaryQuantiles
+}
+
+// ExponentialMaxBuckets returns the maximum number of buckets for each sign of the exponential histograms of the metric
+func (p *ReduceResolution) ExponentialMaxBuckets(name string) int {
+	if maxBuckets := p.MetricOptions(name).MaxBuckets; maxBuckets != 0 {
+		return maxBuckets
+	}
+	return DefaultExponentialMaxBuckets
+}
+
+// GaugeStatistics returns the statistics configured for a gauge, either for its exact name, by the
+// first rule that matches it, or else the default ones. They are looked up once for each gauge
+func (p *ReduceResolution) GaugeStatistics(name string) []Statistic {
+	p.gaugeStatisticsMutex.Lock()
+	defer p.gaugeStatisticsMutex.Unlock()
+	if statistics, ok := p.gaugeStatistics[name]; ok {
+		return statistics
+	}
+	if p.gaugeStatistics == nil {
+		p.gaugeStatistics = make(map[string][]Statistic)
+	}
+	statistics := p.matchGaugeStatistics(name)
+	p.gaugeStatistics[name] = statistics
+	return statistics
+}
+
+func (p *ReduceResolution) matchGaugeStatistics(name string) []Statistic {
+	if statistics, ok := p.Config.MetricsStatistics[strings.ToLower(name)]; ok {
+		return statistics
+	}
+	for i, rule := range p.Config.GaugeAggregationRules {
+		if rule.Matches(name) {
+			p.Logger.Debug("Gauge matched an aggregation rule", zap.String("metric", name), zap.Int("rule", i), zap.String("pattern", rule.Pattern()))
+			return rule.statistics
+		}
+	}
+	if p.Config.DefaultGaugeStatistics == nil {
+		return DefaultGaugeStatistics
+	}
+	return p.Config.DefaultGaugeStatistics
+}
+
+// GaugePassesThrough tells if the datapoints of a gauge are forwarded unchanged
+func (p *ReduceResolution) GaugePassesThrough(name string) bool {
+	for _, statistic := range p.GaugeStatistics(name) {
+		if statistic == StatisticPassthrough {
+			return true
+		}
+	}
+	return false
+}
+
+// SumStatistics returns the statistics configured for a counter, if there are any
+func (p *ReduceResolution) SumStatistics(name string) ([]Statistic, bool) {
+	statistics, ok := p.Config.SumStatistics[strings.ToLower(name)]
+	return statistics, ok
+}
+
+// HistogramStatistics returns the statistics configured for a histogram, if there are any
+func (p *ReduceResolution) HistogramStatistics(name string) ([]Statistic, bool) {
+	statistics, ok := p.Config.HistogramStatistics[strings.ToLower(name)]
+	return statistics, ok
+}
+
+// StatisticMetricName returns the name of the metric emitted for a statistic of a metric, from the
+// statistic-names of the metric, or else from its name-template, the global one, or the default one
+func (p *ReduceResolution) StatisticMetricName(name string, unit string, statistic string, defaultTemplate string) string {
+	options := p.MetricOptions(name)
+	if statisticName, ok := options.StatisticNames[statistic]; ok {
+		return statisticName
+	}
+	template := options.NameTemplate
+	if template == "" {
+		template = p.Config.NameTemplate
+	}
+	if template == "" {
+		template = defaultTemplate
+	}
+	return strings.NewReplacer("{name}", name, "{stat}", statistic, "{unit}", unit).Replace(template)
 }
 
 // ProcessMetrics logs information about incoming metrics
 func (p *ReduceResolution) ProcessMetrics(_ context.Context, metrics pmetric.Metrics) (pmetric.Metrics, error) {
+	if p.Config.Interval > 0 {
+		// The aggregates are kept until the next flush, so nothing is forwarded now
+		p.mutex.Lock()
+		if p.window == nil {
+			p.window = make(map[string]*ResourceContainer)
+		}
+		p.AggregateMetrics(p.window, metrics)
+		p.mutex.Unlock()
+		return metrics, processorhelper.ErrSkipProcessingData
+	}
+
 	if metrics.ResourceMetrics().Len() == 0 {
 		return metrics, nil
 	}
 	var aggregationTimeStamp pcommon.Timestamp = pcommon.NewTimestampFromTime(time.Now())
 
-	var scopesMaps map[string]*ScopeContainer = make(map[string]*ScopeContainer)
+	var resourcesMaps map[string]*ResourceContainer = make(map[string]*ResourceContainer)
+	p.AggregateMetrics(resourcesMaps, metrics)
+
+	return p.CreateMetrics(resourcesMaps, aggregationTimeStamp), nil
+}
+
+// AggregateMetrics adds every datapoint of the received metrics to the aggregates of its resource
+func (p *ReduceResolution) AggregateMetrics(resourcesMaps map[string]*ResourceContainer, metrics pmetric.Metrics) {
 	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
 		resourceMetric := metrics.ResourceMetrics().At(i)
+		resourceKey := CreateResourceKey(resourceMetric)
+		resourceContainer, ok := resourcesMaps[resourceKey]
+
+		if !ok {
+			resourceContainer = CreateResourceContainer(resourceMetric)
+			resourcesMaps[resourceKey] = resourceContainer
+		}
+
 		for j := 0; j < resourceMetric.ScopeMetrics().Len(); j++ {
 			scopeMetric := resourceMetric.ScopeMetrics().At(j)
 			scopeKey := CreateScopeKey(scopeMetric)
-			scopeContainer, ok := scopesMaps[scopeKey]
+			scopeContainer, ok := resourceContainer.scopesMaps[scopeKey]
 
 			if !ok {
 				scopeContainer = CreateScopeContainer(scopeMetric)
-				scopesMaps[scopeKey] = scopeContainer
+				resourceContainer.scopesMaps[scopeKey] = scopeContainer
 			}
 
 			for k := 0; k < scopeMetric.Metrics().Len(); k++ {
@@ -43,22 +199,26 @@ func (p *ReduceResolution) ProcessMetrics(_ context.Context, metrics pmetric.Met
 				switch metric.Type() {
 				// Deal with all gauges
 				case pmetric.MetricTypeGauge:
+					if p.GaugePassesThrough(metric.Name()) {
+						AddLeftoverMetric(scopeContainer, metric)
+						continue
+					}
 					for l := 0; l < metric.Gauge().DataPoints().Len(); l++ {
 						gauge := metric.Gauge().DataPoints().At(l)
 						key := CreateMetricKey(metric, gauge.Attributes())
 						if gauge.ValueType() == pmetric.NumberDataPointValueTypeInt {
 							metricAggregate, ok := scopeContainer.intGaugeAggregate[key]
 							if !ok {
-								scopeContainer.intGaugeAggregate[key] = CreateGaugeAggregate(metric, gauge.Attributes(), gauge.StartTimestamp(), gauge.IntValue())
+								scopeContainer.intGaugeAggregate[key] = CreateGaugeAggregate(metric, gauge.Attributes(), gauge.StartTimestamp(), gauge.Timestamp(), gauge.IntValue(), p.GaugeSettings(metric.Name()), resourceKey)
 							} else {
-								AggregateGauge(metricAggregate, gauge.StartTimestamp(), gauge.IntValue())
+								AggregateGauge(metricAggregate, gauge.StartTimestamp(), gauge.Timestamp(), gauge.IntValue())
 							}
 						} else if gauge.ValueType() == pmetric.NumberDataPointValueTypeDouble {
 							metricAggregate, ok := scopeContainer.floatGaugeAggregate[key]
 							if !ok {
-								scopeContainer.floatGaugeAggregate[key] = CreateGaugeAggregate(metric, gauge.Attributes(), gauge.StartTimestamp(), gauge.DoubleValue())
+								scopeContainer.floatGaugeAggregate[key] = CreateGaugeAggregate(metric, gauge.Attributes(), gauge.StartTimestamp(), gauge.Timestamp(), gauge.DoubleValue(), p.GaugeSettings(metric.Name()), resourceKey)
 							} else {
-								AggregateGauge(metricAggregate, gauge.StartTimestamp(), gauge.DoubleValue())
+								AggregateGauge(metricAggregate, gauge.StartTimestamp(), gauge.Timestamp(), gauge.DoubleValue())
 							}
 						}
 					}
@@ -72,14 +232,14 @@ func (p *ReduceResolution) ProcessMetrics(_ context.Context, metrics pmetric.Met
 						if counter.ValueType() == pmetric.NumberDataPointValueTypeInt {
 							metricAggregate, ok := scopeContainer.intCounterAggregate[key]
 							if !ok {
-								scopeContainer.intCounterAggregate[key] = CreateCounterAggregate(metric, counter.Attributes(), counter.StartTimestamp(), counter.Timestamp(), counter.IntValue())
+								scopeContainer.intCounterAggregate[key] = CreateCounterAggregate(metric, counter.Attributes(), counter.StartTimestamp(), counter.Timestamp(), counter.IntValue(), p.CumulativeResets(metric.Name()))
 							} else {
 								AggregateCounter(metricAggregate, counter.StartTimestamp(), counter.Timestamp(), counter.IntValue())
 							}
 						} else if counter.ValueType() == pmetric.NumberDataPointValueTypeDouble {
 							metricAggregate, ok := scopeContainer.floatCounterAggregate[key]
 							if !ok {
-								scopeContainer.floatCounterAggregate[key] = CreateCounterAggregate(metric, counter.Attributes(), counter.StartTimestamp(), counter.Timestamp(), counter.DoubleValue())
+								scopeContainer.floatCounterAggregate[key] = CreateCounterAggregate(metric, counter.Attributes(), counter.StartTimestamp(), counter.Timestamp(), counter.DoubleValue(), p.CumulativeResets(metric.Name()))
 							} else {
 								AggregateCounter(metricAggregate, counter.StartTimestamp(), counter.Timestamp(), counter.DoubleValue())
 							}
@@ -94,7 +254,8 @@ func (p *ReduceResolution) ProcessMetrics(_ context.Context, metrics pmetric.Met
 
 						metricAggregate, ok := scopeContainer.histogramAggregate[key]
 						if !ok {
-							scopeContainer.histogramAggregate[key] = CreateHistogramAggregate(metric, histogram)
+							scopeContainer.histogramAggregate[key] = CreateHistogramAggregate(metric, histogram, p.CumulativeResets(metric.Name()),
+								p.HistogramBoundsMismatch(metric.Name()), p.MetricOptions(metric.Name()).ExplicitBounds)
 						} else {
 							if AggregateHistogram(metricAggregate, histogram) != 0 {
 								p.Logger.Warn("Histogram datapoint dropped due to mismatch")
@@ -103,48 +264,170 @@ func (p *ReduceResolution) ProcessMetrics(_ context.Context, metrics pmetric.Met
 
 					}
 
+				// Deal with all the exponential histograms
+				case pmetric.MetricTypeExponentialHistogram:
+					for l := 0; l < metric.ExponentialHistogram().DataPoints().Len(); l++ {
+						histogram := metric.ExponentialHistogram().DataPoints().At(l)
+						key := CreateMetricKey(metric, histogram.Attributes())
+
+						metricAggregate, ok := scopeContainer.exponentialHistogramAggregate[key]
+						if !ok {
+							scopeContainer.exponentialHistogramAggregate[key] = CreateExponentialHistogramAggregate(metric, histogram, p.CumulativeResets(metric.Name()), p.ExponentialMaxBuckets(metric.Name()))
+						} else {
+							AggregateExponentialHistogram(metricAggregate, histogram)
+						}
+					}
+
+				// Deal with all the summaries
+				case pmetric.MetricTypeSummary:
+					for l := 0; l < metric.Summary().DataPoints().Len(); l++ {
+						summary := metric.Summary().DataPoints().At(l)
+						key := CreateMetricKey(metric, summary.Attributes())
+
+						metricAggregate, ok := scopeContainer.summaryAggregate[key]
+						if !ok {
+							scopeContainer.summaryAggregate[key] = CreateSummaryAggregate(metric, summary, p.CumulativeResets(metric.Name()), p.SummaryQuantiles(metric.Name()))
+						} else {
+							AggregateSummary(metricAggregate, summary)
+						}
+					}
+
 				// For any non implemented metrics
 				default:
-					scopeContainer.leftoverMetric = append(scopeContainer.leftoverMetric, metric)
+					AddLeftoverMetric(scopeContainer, metric)
 				}
 			}
 		}
 
 	}
+}
+
+// CreateMetrics converts the aggregates into a new set of metrics, with one resource for each container,
+// followed by one resource for each cohort when a rollup is configured
+func (p *ReduceResolution) CreateMetrics(resourcesMaps map[string]*ResourceContainer, aggregationTimeStamp pcommon.Timestamp) pmetric.Metrics {
+	metrics := pmetric.NewMetrics()
+
+	var cohortsMaps map[string]*ResourceContainer = make(map[string]*ResourceContainer)
+	for _, resourceContainer := range resourcesMaps {
+		p.CreateResourceMetrics(metrics, resourceContainer, aggregationTimeStamp)
+		if len(p.Config.RollupResourceAttributes) > 0 {
+			MergeIntoCohort(cohortsMaps, resourceContainer, p)
+		}
+	}
+
+	// The cohorts are only complete once every resource was merged into them
+	for _, cohortContainer := range cohortsMaps {
+		p.CreateResourceMetrics(metrics, cohortContainer, aggregationTimeStamp)
+	}
+
+	p.seriesMutex.Lock()
+	p.expireSeries()
+	p.seriesMutex.Unlock()
 
-	firstResourceMetric := metrics.ResourceMetrics().At(0)
-	metrics.ResourceMetrics().RemoveIf(func(m pmetric.ResourceMetrics) bool { return true })
+	return metrics
+}
 
+// CreateResourceMetrics converts the aggregates of a single resource
+func (p *ReduceResolution) CreateResourceMetrics(metrics pmetric.Metrics, resourceContainer *ResourceContainer, aggregationTimeStamp pcommon.Timestamp) {
 	finalResourceMetric := metrics.ResourceMetrics().AppendEmpty()
-	firstResourceMetric.Resource().CopyTo(finalResourceMetric.Resource())
-	finalResourceMetric.SetSchemaUrl(firstResourceMetric.SchemaUrl())
+	resourceContainer.resource.CopyTo(finalResourceMetric.Resource())
+	finalResourceMetric.SetSchemaUrl(resourceContainer.schemaUrl)
+	resourceKey := CreateResourceKey(finalResourceMetric)
 
-	for _, scopeContainer := range scopesMaps {
+	for _, scopeContainer := range resourceContainer.scopesMaps {
 		scope := finalResourceMetric.ScopeMetrics().AppendEmpty()
 		scope.Scope().SetName(scopeContainer.scopeName)
 		scope.Scope().SetVersion(scopeContainer.scopeVersion)
 
+		// Identifies the series across windows
+		scopeKey := resourceKey + "|" + CreateScopeKey(scope) + "|"
+		ReduceAttributes(scopeContainer, scopeKey, p)
+		statisticsMetrics := make(StatisticsMetrics)
+
 		for _, metricAggregate := range scopeContainer.intGaugeAggregate {
-			CreateGaugeMetrics(scope, metricAggregate, aggregationTimeStamp, p)
+			CreateGaugeMetrics(scope, statisticsMetrics, metricAggregate, aggregationTimeStamp, scopeKey, p)
 		}
 		for _, metricAggregate := range scopeContainer.floatGaugeAggregate {
-			CreateGaugeMetrics(scope, metricAggregate, aggregationTimeStamp, p)
+			CreateGaugeMetrics(scope, statisticsMetrics, metricAggregate, aggregationTimeStamp, scopeKey, p)
 		}
 
-		for _, metricAggregate := range scopeContainer.intCounterAggregate {
-			CreateCounterMetrics(scope, metricAggregate, aggregationTimeStamp)
+		first := scope.Metrics().Len()
+		for key, metricAggregate := range scopeContainer.intCounterAggregate {
+			CreateSumMetrics(scope, statisticsMetrics, metricAggregate, aggregationTimeStamp, scopeKey+key, p)
+		}
+		for key, metricAggregate := range scopeContainer.floatCounterAggregate {
+			CreateSumMetrics(scope, statisticsMetrics, metricAggregate, aggregationTimeStamp, scopeKey+key, p)
+		}
+		for key, metricAggregate := range scopeContainer.histogramAggregate {
+			if p.MetricOptions(metricAggregate.name).HistogramOutput != HistogramOutputStatistics {
+				CreateHistogramMetrics(scope, metricAggregate, aggregationTimeStamp, p)
+			}
+			CreateHistogramStatisticsMetrics(scope, statisticsMetrics, metricAggregate, aggregationTimeStamp, scopeKey+key, p)
 		}
-		for _, metricAggregate := range scopeContainer.floatCounterAggregate {
-			CreateCounterMetrics(scope, metricAggregate, aggregationTimeStamp)
+		for _, metricAggregate := range scopeContainer.exponentialHistogramAggregate {
+			CreateExponentialHistogramMetrics(scope, metricAggregate, aggregationTimeStamp)
 		}
-		for _, metricAggregate := range scopeContainer.histogramAggregate {
-			CreateHistogramMetrics(scope, metricAggregate, aggregationTimeStamp)
+		for _, metricAggregate := range scopeContainer.summaryAggregate {
+			CreateSummaryMetrics(scope, metricAggregate, aggregationTimeStamp)
 		}
+		p.ConvertTemporality(resourceKey, scope, first)
 
 		for _, metric := range scopeContainer.leftoverMetric {
 			metric.MoveTo(scope.Metrics().AppendEmpty())
 		}
 	}
+}
+
+// Start launches the periodic flush when an interval is configured
+func (p *ReduceResolution) Start(_ context.Context, _ component.Host) error {
+	if p.Config.Interval <= 0 {
+		return nil
+	}
+	p.done = make(chan struct{})
+	p.wg.Add(1)
+	go p.flushLoop()
+	return nil
+}
 
-	return metrics, nil
+// Shutdown stops the periodic flush and sends whatever was aggregated since the last one
+func (p *ReduceResolution) Shutdown(ctx context.Context) error {
+	if p.done == nil {
+		return nil
+	}
+	close(p.done)
+	p.wg.Wait()
+	p.done = nil
+	return p.Flush(ctx)
+}
+
+func (p *ReduceResolution) flushLoop() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.Config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.Flush(context.Background()); err != nil {
+				p.Logger.Error("Failed to send aggregated metrics", zap.Error(err))
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// Flush sends the aggregates of the current window to the next consumer and starts a new window
+func (p *ReduceResolution) Flush(ctx context.Context) error {
+	aggregationTimeStamp := pcommon.NewTimestampFromTime(time.Now())
+
+	p.mutex.Lock()
+	window := p.window
+	p.window = nil
+	p.mutex.Unlock()
+
+	if len(window) == 0 {
+		return nil
+	}
+	return p.nextConsumer.ConsumeMetrics(ctx, p.CreateMetrics(window, aggregationTimeStamp))
 }
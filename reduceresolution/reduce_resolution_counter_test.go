@@ -479,3 +479,353 @@ func TestValidate2CounterAggregationDiffScopeDiffVersion(t *testing.T) {
 		assert.True(t, scope2)
 	})
 }
+
+func TestValidateCounterAggregationDropAttributes(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{},
+			MetricsOptions: map[string]MetricOptions{
+				"buffer_underruns": {DropAttributes: []string{"track.id"}},
+			},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	ts := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC))
+
+	var mainMetrics pmetric.Metrics = CreateIntCounterArgument("buffer_underruns", startTS, ts, true, []int64{3, 4, 10})
+	SetDatapointAttributes(mainMetrics, []map[string]any{
+		{"track.id": "1", "codec": "aac"},
+		{"track.id": "2", "codec": "aac"},
+		{"track.id": "3", "codec": "flac"},
+	})
+
+	t.Run("validate cumulative series are summed per remaining attributes", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		var aac bool = false
+		var flac bool = false
+
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 2, scope.Metrics().Len())
+		for k := 0; k < scope.Metrics().Len(); k++ {
+			metric := scope.Metrics().At(k)
+			assert.Equal(t, "buffer_underruns", metric.Name())
+			attributes := metric.Sum().DataPoints().At(0).Attributes()
+			assert.Equal(t, 1, attributes.Len())
+			codec, _ := attributes.Get("codec")
+			switch codec.Str() {
+			case "aac":
+				ValidateIntCounter(t, metric, &aac, true, true, 7, startTS)
+			case "flac":
+				ValidateIntCounter(t, metric, &flac, true, true, 10, startTS)
+			}
+		}
+		assert.True(t, aac)
+		assert.True(t, flac)
+	})
+}
+
+func TestValidateCounterAggregationDropAttributesMissingSeries(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{},
+			MetricsOptions: map[string]MetricOptions{
+				"buffer_underruns": {DropAttributes: []string{"track.id"}},
+			},
+			OutputTemporality: OutputTemporalityDelta,
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	ts := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC))
+
+	for _, window := range []struct {
+		name   string
+		values []int64
+		tracks []map[string]any
+		delta  int64
+	}{
+		{"validate the first window emits the whole value", []int64{10, 5}, []map[string]any{{"track.id": "1"}, {"track.id": "2"}}, 15},
+		{"validate a missing series does not look like a reset", []int64{12}, []map[string]any{{"track.id": "1"}}, 2},
+		{"validate a returning series only adds its increase", []int64{13, 6}, []map[string]any{{"track.id": "1"}, {"track.id": "2"}}, 2},
+	} {
+		t.Run(window.name, func(t *testing.T) {
+			metrics := CreateIntCounterArgument("buffer_underruns", startTS, ts, true, window.values)
+			SetDatapointAttributes(metrics, window.tracks)
+			finalMetrics, error := processor.ProcessMetrics(nil, metrics)
+
+			assert.NoError(t, error)
+			scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+			assert.Equal(t, 1, scope.Metrics().Len())
+			dp := scope.Metrics().At(0).Sum().DataPoints().At(0)
+			assert.Equal(t, 0, dp.Attributes().Len())
+			assert.Equal(t, window.delta, dp.IntValue())
+			ts = pcommon.NewTimestampFromTime(ts.AsTime().Add(time.Minute))
+		})
+	}
+}
+
+func TestValidateCounterAggregationCumulativeResets(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	rebootTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 35, 0, time.UTC))
+	timestamp := func(second int) pcommon.Timestamp {
+		return pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, second, 0, time.UTC))
+	}
+
+	for _, test := range []struct {
+		name     string
+		resets   string
+		counters []CounterArg[int64]
+		value    int64
+		startTS  pcommon.Timestamp
+	}{
+		{
+			"validate the increase before a restart is accumulated",
+			CumulativeResetsAccumulate,
+			[]CounterArg[int64]{
+				{"testcounter", startTS, timestamp(20), true, true, []int64{10}},
+				{"testcounter", startTS, timestamp(30), true, true, []int64{15}},
+				{"testcounter", rebootTS, timestamp(40), true, true, []int64{4}},
+				{"testcounter", rebootTS, timestamp(50), true, true, []int64{6}},
+			},
+			21,
+			startTS,
+		},
+		{
+			"validate a restart starts a new series",
+			CumulativeResetsRestart,
+			[]CounterArg[int64]{
+				{"testcounter", startTS, timestamp(20), true, true, []int64{10}},
+				{"testcounter", startTS, timestamp(30), true, true, []int64{15}},
+				{"testcounter", rebootTS, timestamp(40), true, true, []int64{4}},
+				{"testcounter", rebootTS, timestamp(50), true, true, []int64{6}},
+			},
+			6,
+			rebootTS,
+		},
+		{
+			"validate a drop of the value is accumulated",
+			CumulativeResetsAccumulate,
+			[]CounterArg[int64]{
+				{"testcounter", startTS, timestamp(20), true, true, []int64{10}},
+				{"testcounter", startTS, timestamp(40), true, true, []int64{3}},
+			},
+			13,
+			startTS,
+		},
+		{
+			"validate a drop of the value restarts at the previous datapoint",
+			CumulativeResetsRestart,
+			[]CounterArg[int64]{
+				{"testcounter", startTS, timestamp(20), true, true, []int64{10}},
+				{"testcounter", startTS, timestamp(40), true, true, []int64{3}},
+			},
+			3,
+			timestamp(20),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var processor *ReduceResolution = &ReduceResolution{
+				Logger: logger,
+				Config: ProcessedConfig{
+					MetricsStatistics: map[string][]Statistic{},
+					CumulativeResets:  test.resets,
+				},
+			}
+			var mainMetrics pmetric.Metrics = CreateArgument(
+				MetricArg{
+					[]ResourceMetricsArg{
+						{
+							[]ScopeArg{
+								{
+									"testscope",
+									"1.0",
+									[]GaugeArg[float64]{},
+									[]GaugeArg[int64]{},
+									[]CounterArg[float64]{},
+									test.counters,
+									[]HistogramArg{},
+								},
+							},
+						},
+					},
+				},
+			)
+
+			finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+			assert.NoError(t, error)
+			var counter bool = false
+			scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+			assert.Equal(t, 1, scope.Metrics().Len())
+			ValidateIntCounter(t, scope.Metrics().At(0), &counter, true, true, test.value, test.startTS)
+			assert.True(t, counter)
+		})
+	}
+}
+
+func TestValidateCounterRateOutput(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	t.Run("validate the rate of a cumulative counter uses the previous window", func(t *testing.T) {
+		var processor *ReduceResolution = &ReduceResolution{
+			Logger: logger,
+			Config: ProcessedConfig{
+				MetricsStatistics: map[string][]Statistic{},
+				MetricsOptions: map[string]MetricOptions{
+					"bytes_sent": {CounterOutput: CounterOutputRate},
+				},
+			},
+		}
+		for _, window := range []struct {
+			ts    pcommon.Timestamp
+			value int64
+			rate  float64
+		}{
+			{pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC)), 100, 10},
+			{pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 1, 10, 0, time.UTC)), 1300, 20},
+		} {
+			mainMetrics := CreateIntCounterArgument("bytes_sent", startTS, window.ts, true, []int64{window.value})
+			mainMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).SetUnit("By")
+			finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+			assert.NoError(t, error)
+			scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+			assert.Equal(t, 1, scope.Metrics().Len())
+			metric := scope.Metrics().At(0)
+			assert.Equal(t, "bytes_sent_rate", metric.Name())
+			assert.Equal(t, "By/s", metric.Unit())
+			var rate bool = false
+			ValidateDoubleGauge(t, metric, &rate, window.rate, startTS)
+			assert.True(t, rate)
+		}
+	})
+
+	t.Run("validate the rate of a delta counter uses its start", func(t *testing.T) {
+		var processor *ReduceResolution = &ReduceResolution{
+			Logger: logger,
+			Config: ProcessedConfig{
+				MetricsStatistics: map[string][]Statistic{},
+				MetricsOptions: map[string]MetricOptions{
+					"bytes_sent": {CounterOutput: CounterOutputRate},
+				},
+			},
+		}
+		ts := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC))
+		finalMetrics, error := processor.ProcessMetrics(nil, CreateIntCounterArgument("bytes_sent", startTS, ts, false, []int64{10, 20}))
+
+		assert.NoError(t, error)
+		var rate bool = false
+		metric := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+		assert.Equal(t, "bytes_sent_rate", metric.Name())
+		assert.Equal(t, "1/s", metric.Unit())
+		ValidateDoubleGauge(t, metric, &rate, 1, startTS)
+		assert.True(t, rate)
+	})
+}
+
+func TestValidateCounterMaxRateDropAttributes(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{},
+			SumStatistics:     map[string][]Statistic{"bytes_sent": {StatisticMaxRate}},
+			MetricsOptions:    map[string]MetricOptions{"bytes_sent": {DropAttributes: []string{"path"}}},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+	ts := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+
+	t.Run("validate the max rate is the highest one of the merged series", func(t *testing.T) {
+		metrics := CreateIntCounterArgument("bytes_sent", startTS, ts, false, []int64{10, 20})
+		SetDatapointAttributes(metrics, []map[string]any{{"path": "/a"}, {"path": "/b"}})
+		finalMetrics, error := processor.ProcessMetrics(nil, metrics)
+
+		assert.NoError(t, error)
+		var maxRate bool = false
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 1, scope.Metrics().Len())
+		ValidateDoubleGauge(t, scope.Metrics().At(0), &maxRate, 2, startTS)
+		assert.True(t, maxRate)
+	})
+}
+
+func TestValidateCounterSumStatistics(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{},
+			SumStatistics: map[string][]Statistic{
+				"bytes_sent": {StatisticIncrease, StatisticRate, StatisticMaxRate, StatisticLast},
+			},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+	ts := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+
+	t.Run("validate the statistics of a delta counter replace the counter", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, CreateIntCounterArgument("bytes_sent", startTS, ts, false, []int64{10, 20}))
+
+		assert.NoError(t, error)
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 4, scope.Metrics().Len())
+		var increase, rate, maxRate, last bool = false, false, false, false
+		for i := 0; i < scope.Metrics().Len(); i++ {
+			metric := scope.Metrics().At(i)
+			switch metric.Name() {
+			case "bytes_sent_increase":
+				ValidateDoubleGauge(t, metric, &increase, 30, startTS)
+			case "bytes_sent_rate":
+				ValidateDoubleGauge(t, metric, &rate, 3, startTS)
+			case "bytes_sent_max_rate":
+				ValidateDoubleGauge(t, metric, &maxRate, 2, startTS)
+			case "bytes_sent_last":
+				ValidateIntGauge(t, metric, &last, 20, startTS)
+			default:
+				assert.Fail(t, "unexpected metric", metric.Name())
+			}
+		}
+		assert.True(t, increase)
+		assert.True(t, rate)
+		assert.True(t, maxRate)
+		assert.True(t, last)
+	})
+	t.Run("validate the statistics in another unit keep their own gauge with the statistic attribute", func(t *testing.T) {
+		processor.Config.StatisticNaming = StatisticNamingAttribute
+		processor.Config.StatisticAttribute = "stat"
+		metrics := CreateIntCounterArgument("bytes_sent", startTS, ts, false, []int64{10, 20})
+		metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).SetUnit("By")
+		finalMetrics, error := processor.ProcessMetrics(nil, metrics)
+
+		assert.NoError(t, error)
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		expected := map[string]struct {
+			unit  string
+			stats []string
+		}{
+			"bytes_sent_stat":     {"By", []string{"increase", "last"}},
+			"bytes_sent_rate":     {"By/s", []string{"rate"}},
+			"bytes_sent_max_rate": {"By/s", []string{"max_rate"}},
+		}
+		assert.Equal(t, len(expected), scope.Metrics().Len())
+		for i := 0; i < scope.Metrics().Len(); i++ {
+			metric := scope.Metrics().At(i)
+			want, ok := expected[metric.Name()]
+			assert.True(t, ok, metric.Name())
+			assert.Equal(t, want.unit, metric.Unit(), metric.Name())
+			assert.Equal(t, len(want.stats), metric.Gauge().DataPoints().Len(), metric.Name())
+			for l := 0; l < metric.Gauge().DataPoints().Len(); l++ {
+				stat, _ := metric.Gauge().DataPoints().At(l).Attributes().Get("stat")
+				assert.Contains(t, want.stats, stat.Str())
+			}
+		}
+	})
+}
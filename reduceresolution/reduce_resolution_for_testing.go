@@ -79,6 +79,25 @@ func ValidateHistogram(t *testing.T, metric pmetric.Metric, wasChecked *bool, is
 	*wasChecked = true
 }
 
+func ValidateExponentialHistogram(t *testing.T, metric pmetric.Metric, wasChecked *bool, isCumulative bool, value ExponentialHistogramValue, startTs pcommon.Timestamp) {
+	dp := metric.ExponentialHistogram().DataPoints().At(0)
+	assert.Equal(t, value.count, dp.Count())
+	assert.Equal(t, value.sum, dp.Sum())
+	assert.Equal(t, value.max, dp.Max())
+	assert.Equal(t, value.min, dp.Min())
+	assert.Equal(t, isCumulative, metric.ExponentialHistogram().AggregationTemporality() == pmetric.AggregationTemporalityCumulative)
+	assert.Equal(t, startTs, dp.StartTimestamp())
+
+	assert.Equal(t, value.scale, dp.Scale())
+	assert.Equal(t, value.zeroCount, dp.ZeroCount())
+	assert.Equal(t, value.positive.offset, dp.Positive().Offset())
+	assert.Equal(t, value.positive.counts, dp.Positive().BucketCounts().AsRaw())
+	assert.Equal(t, value.negative.offset, dp.Negative().Offset())
+	assert.Equal(t, value.negative.counts, dp.Negative().BucketCounts().AsRaw())
+
+	*wasChecked = true
+}
+
 type GaugeArg[T GaugeValue] struct {
 	name    string
 	startTS pcommon.Timestamp
@@ -112,6 +131,25 @@ type HistogramArg struct {
 	values     []HistogramValue
 }
 
+type ExponentialHistogramValue struct {
+	count     uint64
+	sum       float64
+	max       float64
+	min       float64
+	scale     int32
+	zeroCount uint64
+	positive  ExponentialBuckets
+	negative  ExponentialBuckets
+}
+
+type ExponentialHistogramArg struct {
+	name       string
+	startTS    pcommon.Timestamp
+	ts         pcommon.Timestamp
+	cumulative bool
+	values     []ExponentialHistogramValue
+}
+
 type ScopeArg struct {
 	name    string
 	version string
@@ -229,3 +267,196 @@ func CreateArgument(metricArg MetricArg) pmetric.Metrics {
 
 	return res
 }
+
+func CreateIntGaugeArgument(name string, startTS pcommon.Timestamp, values []int64) pmetric.Metrics {
+	return CreateArgument(
+		MetricArg{
+			[]ResourceMetricsArg{
+				{
+					[]ScopeArg{
+						{
+							"testscope",
+							"1.0",
+							[]GaugeArg[float64]{},
+							[]GaugeArg[int64]{
+								{
+									name,
+									startTS,
+									startTS,
+									values,
+								},
+							},
+							[]CounterArg[float64]{},
+							[]CounterArg[int64]{},
+							[]HistogramArg{},
+						},
+					},
+				},
+			},
+		},
+	)
+}
+
+func CreateIntCounterArgument(name string, startTS pcommon.Timestamp, ts pcommon.Timestamp, cumulative bool, values []int64) pmetric.Metrics {
+	return CreateArgument(
+		MetricArg{
+			[]ResourceMetricsArg{
+				{
+					[]ScopeArg{
+						{
+							"testscope",
+							"1.0",
+							[]GaugeArg[float64]{},
+							[]GaugeArg[int64]{},
+							[]CounterArg[float64]{},
+							[]CounterArg[int64]{
+								{
+									name,
+									startTS,
+									ts,
+									cumulative,
+									true,
+									values,
+								},
+							},
+							[]HistogramArg{},
+						},
+					},
+				},
+			},
+		},
+	)
+}
+
+// Sets the attributes of every datapoint of the first metric, in the same order as the datapoints
+func SetDatapointAttributes(metrics pmetric.Metrics, attributes []map[string]any) {
+	metric := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	for i, dpAttributes := range attributes {
+		switch metric.Type() {
+		case pmetric.MetricTypeGauge:
+			_ = metric.Gauge().DataPoints().At(i).Attributes().FromRaw(dpAttributes)
+		case pmetric.MetricTypeSum:
+			_ = metric.Sum().DataPoints().At(i).Attributes().FromRaw(dpAttributes)
+		case pmetric.MetricTypeHistogram:
+			_ = metric.Histogram().DataPoints().At(i).Attributes().FromRaw(dpAttributes)
+		case pmetric.MetricTypeSummary:
+			_ = metric.Summary().DataPoints().At(i).Attributes().FromRaw(dpAttributes)
+		}
+	}
+}
+
+func CreateDoubleGaugeArgument(name string, startTS pcommon.Timestamp, values []float64) pmetric.Metrics {
+	return CreateArgument(
+		MetricArg{
+			[]ResourceMetricsArg{
+				{
+					[]ScopeArg{
+						{
+							"testscope",
+							"1.0",
+							[]GaugeArg[float64]{
+								{
+									name,
+									startTS,
+									startTS,
+									values,
+								},
+							},
+							[]GaugeArg[int64]{},
+							[]CounterArg[float64]{},
+							[]CounterArg[int64]{},
+							[]HistogramArg{},
+						},
+					},
+				},
+			},
+		},
+	)
+}
+
+// Sets the timestamp of every datapoint of the first gauge, in the same order as the datapoints
+func SetGaugeTimestamps(metrics pmetric.Metrics, timestamps []pcommon.Timestamp) {
+	metric := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	for i, timestamp := range timestamps {
+		metric.Gauge().DataPoints().At(i).SetTimestamp(timestamp)
+	}
+}
+
+func CreateExponentialHistogramArgument(histograms []ExponentialHistogramArg) pmetric.Metrics {
+	res := pmetric.NewMetrics()
+	scopeMetric := res.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	scopeMetric.Scope().SetName("testscope")
+	scopeMetric.Scope().SetVersion("1.0")
+	for _, metricArg := range histograms {
+		metric := scopeMetric.Metrics().AppendEmpty()
+		metric.SetName(metricArg.name)
+		histogram := metric.SetEmptyExponentialHistogram()
+		if metricArg.cumulative {
+			histogram.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		} else {
+			histogram.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		}
+		for _, value := range metricArg.values {
+			dp := histogram.DataPoints().AppendEmpty()
+			dp.SetCount(value.count)
+			dp.SetSum(value.sum)
+			dp.SetMax(value.max)
+			dp.SetMin(value.min)
+			dp.SetScale(value.scale)
+			dp.SetZeroCount(value.zeroCount)
+			dp.Positive().SetOffset(value.positive.offset)
+			dp.Positive().BucketCounts().FromRaw(value.positive.counts)
+			dp.Negative().SetOffset(value.negative.offset)
+			dp.Negative().BucketCounts().FromRaw(value.negative.counts)
+			dp.SetStartTimestamp(metricArg.startTS)
+			dp.SetTimestamp(metricArg.ts)
+		}
+	}
+	return res
+}
+
+type SummaryValue struct {
+	ts        pcommon.Timestamp
+	count     uint64
+	sum       float64
+	quantiles []SummaryQuantile
+}
+
+func CreateSummaryArgument(name string, startTS pcommon.Timestamp, values []SummaryValue) pmetric.Metrics {
+	res := pmetric.NewMetrics()
+	scopeMetric := res.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	scopeMetric.Scope().SetName("testscope")
+	scopeMetric.Scope().SetVersion("1.0")
+	metric := scopeMetric.Metrics().AppendEmpty()
+	metric.SetName(name)
+	summary := metric.SetEmptySummary()
+	for _, value := range values {
+		dp := summary.DataPoints().AppendEmpty()
+		dp.SetCount(value.count)
+		dp.SetSum(value.sum)
+		for _, quantile := range value.quantiles {
+			quantileValue := dp.QuantileValues().AppendEmpty()
+			quantileValue.SetQuantile(quantile.quantile)
+			quantileValue.SetValue(quantile.value)
+		}
+		dp.SetStartTimestamp(startTS)
+		dp.SetTimestamp(value.ts)
+	}
+	return res
+}
+
+func ValidateSummary(t *testing.T, metric pmetric.Metric, wasChecked *bool, count uint64, sum float64, quantiles []SummaryQuantile, startTs pcommon.Timestamp) {
+	assert.Equal(t, pmetric.MetricTypeSummary, metric.Type())
+	dp := metric.Summary().DataPoints().At(0)
+	assert.Equal(t, count, dp.Count())
+	assert.Equal(t, sum, dp.Sum())
+	assert.Equal(t, startTs, dp.StartTimestamp())
+
+	assert.Equal(t, len(quantiles), dp.QuantileValues().Len())
+	for i := 0; i < len(quantiles) && i < dp.QuantileValues().Len(); i++ {
+		assert.Equal(t, quantiles[i].quantile, dp.QuantileValues().At(i).Quantile())
+		assert.Equal(t, quantiles[i].value, dp.QuantileValues().At(i).Value())
+	}
+
+	*wasChecked = true
+}
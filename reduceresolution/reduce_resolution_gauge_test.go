@@ -5,6 +5,7 @@
 package reduceresolution
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -12,13 +13,14 @@ import (
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestValidateIntGaugeAggregation(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	var processor *ReduceResolution = &ReduceResolution{
 		Logger: logger,
-		Config: ProcessedConfig{map[string][]string{}},
+		Config: ProcessedConfig{MetricsStatistics: map[string][]Statistic{}},
 	}
 
 	var mainMetrics pmetric.Metrics = CreateArgument(
@@ -84,7 +86,7 @@ func TestValidate2GaugeAggregationDifferentScope(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	var processor *ReduceResolution = &ReduceResolution{
 		Logger: logger,
-		Config: ProcessedConfig{map[string][]string{}},
+		Config: ProcessedConfig{MetricsStatistics: map[string][]Statistic{}},
 	}
 
 	var mainMetrics pmetric.Metrics = CreateArgument(
@@ -168,7 +170,7 @@ func TestValidate2GaugeAggregationSameScope(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	var processor *ReduceResolution = &ReduceResolution{
 		Logger: logger,
-		Config: ProcessedConfig{map[string][]string{}},
+		Config: ProcessedConfig{MetricsStatistics: map[string][]Statistic{}},
 	}
 
 	var mainMetrics pmetric.Metrics = CreateArgument(
@@ -235,7 +237,7 @@ func TestValidate2GaugeDoubleAggregationSameScope(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	var processor *ReduceResolution = &ReduceResolution{
 		Logger: logger,
-		Config: ProcessedConfig{map[string][]string{}},
+		Config: ProcessedConfig{MetricsStatistics: map[string][]Statistic{}},
 	}
 
 	var mainMetrics pmetric.Metrics = CreateArgument(
@@ -302,7 +304,7 @@ func TestValidate2GaugeAggregationDiffScopeDiffName(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	var processor *ReduceResolution = &ReduceResolution{
 		Logger: logger,
-		Config: ProcessedConfig{map[string][]string{}},
+		Config: ProcessedConfig{MetricsStatistics: map[string][]Statistic{}},
 	}
 
 	var mainMetrics pmetric.Metrics = CreateArgument(
@@ -410,7 +412,7 @@ func TestValidate2GaugeAggregationDiffScopeDiffVersion(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	var processor *ReduceResolution = &ReduceResolution{
 		Logger: logger,
-		Config: ProcessedConfig{map[string][]string{}},
+		Config: ProcessedConfig{MetricsStatistics: map[string][]Statistic{}},
 	}
 
 	var mainMetrics pmetric.Metrics = CreateArgument(
@@ -520,10 +522,10 @@ func TestValidate2GaugeAggregationRealMaxMin(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	var processor *ReduceResolution = &ReduceResolution{
 		Logger: logger,
-		Config: ProcessedConfig{map[string][]string{}},
+		Config: ProcessedConfig{MetricsStatistics: map[string][]Statistic{}},
 	}
 
-	processor.Config.MetricsStatistics["testmetric"] = []string{"max", "min"}
+	processor.Config.MetricsStatistics["testmetric"] = []Statistic{"max", "min"}
 
 	var mainMetrics pmetric.Metrics = CreateArgument(
 		MetricArg{
@@ -589,10 +591,10 @@ func TestValidate2GaugeAggregationRealMaxMinOnlyNegatives(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	var processor *ReduceResolution = &ReduceResolution{
 		Logger: logger,
-		Config: ProcessedConfig{map[string][]string{}},
+		Config: ProcessedConfig{MetricsStatistics: map[string][]Statistic{}},
 	}
 
-	processor.Config.MetricsStatistics["testmetric"] = []string{"max", "min"}
+	processor.Config.MetricsStatistics["testmetric"] = []Statistic{"max", "min"}
 
 	var mainMetrics pmetric.Metrics = CreateArgument(
 		MetricArg{
@@ -658,9 +660,9 @@ func TestValidate2GaugeAggregationRealMaxMinOnlyPositives(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	var processor *ReduceResolution = &ReduceResolution{
 		Logger: logger,
-		Config: ProcessedConfig{map[string][]string{}},
+		Config: ProcessedConfig{MetricsStatistics: map[string][]Statistic{}},
 	}
-	processor.Config.MetricsStatistics["testmetric"] = []string{"max", "min"}
+	processor.Config.MetricsStatistics["testmetric"] = []Statistic{"max", "min"}
 
 	var mainMetrics pmetric.Metrics = CreateArgument(
 		MetricArg{
@@ -726,7 +728,7 @@ func TestValidate2GaugeAggregationAbsMaxMinOnlyNegatives(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	var processor *ReduceResolution = &ReduceResolution{
 		Logger: logger,
-		Config: ProcessedConfig{map[string][]string{}},
+		Config: ProcessedConfig{MetricsStatistics: map[string][]Statistic{}},
 	}
 
 	var mainMetrics pmetric.Metrics = CreateArgument(
@@ -793,9 +795,9 @@ func TestValidate2GaugeAggregationDifferentTypes(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	var processor *ReduceResolution = &ReduceResolution{
 		Logger: logger,
-		Config: ProcessedConfig{map[string][]string{}},
+		Config: ProcessedConfig{MetricsStatistics: map[string][]Statistic{}},
 	}
-	processor.Config.MetricsStatistics["testmetric"] = []string{"count", "sum", "max", "avg", "abs_min"}
+	processor.Config.MetricsStatistics["testmetric"] = []Statistic{"count", "sum", "max", "avg", "abs_min"}
 
 	var mainMetrics pmetric.Metrics = CreateArgument(
 		MetricArg{
@@ -868,3 +870,652 @@ func TestValidate2GaugeAggregationDifferentTypes(t *testing.T) {
 		}
 	})
 }
+
+func TestValidateGaugeAggregationKeepAttributes(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{"testmetric": {"max", "min", "count"}},
+			MetricsOptions: map[string]MetricOptions{
+				"testmetric": {KeepAttributes: []string{"codec"}},
+			},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	var mainMetrics pmetric.Metrics = CreateIntGaugeArgument("testmetric", startTS, []int64{3, 8, -2})
+	SetDatapointAttributes(mainMetrics, []map[string]any{
+		{"track.id": "1", "codec": "aac"},
+		{"track.id": "2", "codec": "aac"},
+		{"track.id": "3"},
+	})
+
+	t.Run("validate series are merged on the kept attributes", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		checked := map[string]bool{}
+
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 6, scope.Metrics().Len())
+		for k := 0; k < scope.Metrics().Len(); k++ {
+			metric := scope.Metrics().At(k)
+			attributes := metric.Gauge().DataPoints().At(0).Attributes()
+			_, hasTrack := attributes.Get("track.id")
+			assert.False(t, hasTrack)
+			_, hasCodec := attributes.Get("codec")
+			key := metric.Name() + "@" + CreateAttributesKey(attributes)
+			wasChecked := checked[key]
+			switch {
+			case hasCodec && metric.Name() == "testmetric_gauge_max":
+				ValidateIntGauge(t, metric, &wasChecked, 8, startTS)
+			case hasCodec && metric.Name() == "testmetric_gauge_min":
+				ValidateIntGauge(t, metric, &wasChecked, 3, startTS)
+			case hasCodec && metric.Name() == "testmetric_gauge_count":
+				ValidateIntGauge(t, metric, &wasChecked, 2, startTS)
+			case !hasCodec && metric.Name() == "testmetric_gauge_count":
+				ValidateIntGauge(t, metric, &wasChecked, 1, startTS)
+			case !hasCodec:
+				ValidateIntGauge(t, metric, &wasChecked, -2, startTS)
+			}
+			checked[key] = wasChecked
+		}
+		assert.Equal(t, 6, len(checked))
+	})
+}
+
+func TestValidateGaugeAggregationPercentiles(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{"jitter": {"p50", "p99", "quantile(0.999)", "p0"}},
+			RelativeAccuracy:  0.01,
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	values := make([]float64, 0, 1000)
+	for i := 1000; i >= 1; i-- {
+		values = append(values, float64(i))
+	}
+	var mainMetrics pmetric.Metrics = CreateDoubleGaugeArgument("jitter", startTS, values)
+
+	t.Run("validate percentiles are within the relative accuracy", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		expected := map[string]float64{
+			"jitter_gauge_p50":   500,
+			"jitter_gauge_p99":   990,
+			"jitter_gauge_p99_9": 999,
+			"jitter_gauge_p0":    1,
+		}
+
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, len(expected), scope.Metrics().Len())
+		for k := 0; k < scope.Metrics().Len(); k++ {
+			metric := scope.Metrics().At(k)
+			value, ok := expected[metric.Name()]
+			assert.True(t, ok, metric.Name())
+			assert.InEpsilon(t, value, metric.Gauge().DataPoints().At(0).DoubleValue(), 0.01)
+		}
+	})
+}
+
+func TestValidateGaugeAggregationDispersion(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{"signal": {"stddev", "variance", "range"}},
+			MetricsOptions: map[string]MetricOptions{
+				"signal": {DropAttributes: []string{"antenna"}},
+			},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	// Large offset, where a naive sum of squares would lose the variance
+	var mainMetrics pmetric.Metrics = CreateIntGaugeArgument("signal", startTS, []int64{1e9 + 2, 1e9 + 4, 1e9 + 4, 1e9 + 4, 1e9 + 5, 1e9 + 5, 1e9 + 7, 1e9 + 9})
+	SetDatapointAttributes(mainMetrics, []map[string]any{
+		{"antenna": "1"}, {"antenna": "1"}, {"antenna": "1"}, {"antenna": "2"},
+		{"antenna": "2"}, {"antenna": "2"}, {"antenna": "2"}, {"antenna": "2"},
+	})
+
+	t.Run("validate dispersion of merged series", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		var stddev bool = false
+		var variance bool = false
+		var valueRange bool = false
+
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 3, scope.Metrics().Len())
+		for k := 0; k < scope.Metrics().Len(); k++ {
+			metric := scope.Metrics().At(k)
+			switch metric.Name() {
+			case "signal_gauge_stddev":
+				ValidateDoubleGauge(t, metric, &stddev, 2, startTS)
+			case "signal_gauge_variance":
+				ValidateDoubleGauge(t, metric, &variance, 4, startTS)
+			case "signal_gauge_range":
+				ValidateIntGauge(t, metric, &valueRange, 7, startTS)
+			}
+		}
+		assert.True(t, stddev)
+		assert.True(t, variance)
+		assert.True(t, valueRange)
+	})
+}
+
+func TestValidateGaugeAggregationFirstLast(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{"volume": {"first", "last"}},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+	firstTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 5, 0, time.UTC))
+	lastTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 25, 0, time.UTC))
+
+	// Arrival order does not match the timestamps
+	var mainMetrics pmetric.Metrics = CreateIntGaugeArgument("volume", startTS, []int64{30, 45, 20, 35})
+	SetGaugeTimestamps(mainMetrics, []pcommon.Timestamp{
+		pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC)),
+		lastTS,
+		firstTS,
+		pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 15, 0, time.UTC)),
+	})
+
+	t.Run("validate first and last follow the datapoint timestamps", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		var first bool = false
+		var last bool = false
+
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 2, scope.Metrics().Len())
+		for k := 0; k < scope.Metrics().Len(); k++ {
+			metric := scope.Metrics().At(k)
+			switch metric.Name() {
+			case "volume_gauge_first":
+				ValidateIntGauge(t, metric, &first, 20, startTS)
+				assert.Equal(t, firstTS, metric.Gauge().DataPoints().At(0).Timestamp())
+			case "volume_gauge_last":
+				ValidateIntGauge(t, metric, &last, 45, startTS)
+				assert.Equal(t, lastTS, metric.Gauge().DataPoints().At(0).Timestamp())
+			}
+		}
+		assert.True(t, first)
+		assert.True(t, last)
+	})
+}
+
+func TestValidateGaugeAggregationTimeWeightedAverage(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{"volume": {"twa", "avg"}},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+	windowEnd := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 1, 0, 0, time.UTC))
+
+	// Reported on change
+	var mainMetrics pmetric.Metrics = CreateDoubleGaugeArgument("volume", startTS, []float64{10, 20, 40})
+	SetGaugeTimestamps(mainMetrics, []pcommon.Timestamp{
+		startTS,
+		pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC)),
+		pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 40, 0, time.UTC)),
+	})
+
+	t.Run("validate values are weighted by how long they lasted", func(t *testing.T) {
+		var resourcesMaps map[string]*ResourceContainer = make(map[string]*ResourceContainer)
+		processor.AggregateMetrics(resourcesMaps, mainMetrics)
+		finalMetrics := processor.CreateMetrics(resourcesMaps, windowEnd)
+
+		var twa bool = false
+		var avg bool = false
+
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 2, scope.Metrics().Len())
+		for k := 0; k < scope.Metrics().Len(); k++ {
+			metric := scope.Metrics().At(k)
+			switch metric.Name() {
+			case "volume_gauge_twa":
+				ValidateDoubleGauge(t, metric, &twa, 25, startTS)
+			case "volume_gauge_avg":
+				assert.InDelta(t, 23.333, metric.Gauge().DataPoints().At(0).DoubleValue(), 0.001)
+				avg = true
+			}
+		}
+		assert.True(t, twa)
+		assert.True(t, avg)
+	})
+
+	t.Run("validate the last value of the previous window is in effect until the first sample", func(t *testing.T) {
+		var nextMetrics pmetric.Metrics = CreateDoubleGaugeArgument("volume", startTS, []float64{10})
+		SetGaugeTimestamps(nextMetrics, []pcommon.Timestamp{
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 1, 30, 0, time.UTC)),
+		})
+		var resourcesMaps map[string]*ResourceContainer = make(map[string]*ResourceContainer)
+		processor.AggregateMetrics(resourcesMaps, nextMetrics)
+		finalMetrics := processor.CreateMetrics(resourcesMaps, pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 2, 0, 0, time.UTC)))
+
+		var twa bool = false
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		for k := 0; k < scope.Metrics().Len(); k++ {
+			metric := scope.Metrics().At(k)
+			if metric.Name() == "volume_gauge_twa" {
+				// 40 from the end of the previous window for 30 seconds, then 10 for 30 seconds
+				ValidateDoubleGauge(t, metric, &twa, 25, startTS)
+			}
+		}
+		assert.True(t, twa)
+	})
+}
+
+func TestValidateGaugeAggregationHistogramOutput(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{},
+			MetricsOptions: map[string]MetricOptions{
+				"speed": {GaugeOutput: GaugeOutputHistogram, ExplicitBounds: []float64{0, 10, 20}},
+			},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	var mainMetrics pmetric.Metrics = CreateIntGaugeArgument("speed", startTS, []int64{5, 10, 15, 25, -1})
+
+	t.Run("validate gauge is emitted as a single histogram", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		var histogram bool = false
+
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 1, scope.Metrics().Len())
+		metric := scope.Metrics().At(0)
+		assert.Equal(t, pmetric.MetricTypeHistogram, metric.Type())
+		assert.Equal(t, "speed", metric.Name())
+		ValidateHistogram(t, metric, &histogram, false, []float64{0, 10, 20}, HistogramValue{5, 54, 25, -1, []uint64{1, 2, 1, 1}}, startTS)
+		assert.True(t, histogram)
+	})
+}
+
+func TestValidateGaugeAggregationExponentialHistogramOutput(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{},
+			MetricsOptions: map[string]MetricOptions{
+				"latency": {GaugeOutput: GaugeOutputExponentialHistogram, MaxBuckets: 4, DropAttributes: []string{"track.id"}},
+			},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	values := []float64{1, 2, 4, 0, 1000, 1e6, -3}
+	var mainMetrics pmetric.Metrics = CreateDoubleGaugeArgument("latency", startTS, values)
+	SetDatapointAttributes(mainMetrics, []map[string]any{
+		{"track.id": "1"}, {"track.id": "1"}, {"track.id": "1"}, {"track.id": "2"},
+		{"track.id": "2"}, {"track.id": "2"}, {"track.id": "3"},
+	})
+
+	t.Run("validate gauge is emitted as a single exponential histogram", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 1, scope.Metrics().Len())
+		metric := scope.Metrics().At(0)
+		assert.Equal(t, pmetric.MetricTypeExponentialHistogram, metric.Type())
+		assert.Equal(t, "latency", metric.Name())
+		assert.Equal(t, pmetric.AggregationTemporalityDelta, metric.ExponentialHistogram().AggregationTemporality())
+
+		dp := metric.ExponentialHistogram().DataPoints().At(0)
+		assert.Equal(t, startTS, dp.StartTimestamp())
+		assert.Equal(t, uint64(7), dp.Count())
+		assert.Equal(t, 1001004.0, dp.Sum())
+		assert.Equal(t, -3.0, dp.Min())
+		assert.Equal(t, 1e6, dp.Max())
+		assert.Equal(t, uint64(1), dp.ZeroCount())
+		assert.LessOrEqual(t, dp.Positive().BucketCounts().Len(), 4)
+
+		// Every value must be counted in the bucket that contains it
+		base := math.Pow(2, math.Pow(2, -float64(dp.Scale())))
+		expected := map[int]uint64{}
+		for _, value := range values {
+			if value > 0 {
+				index := int(ExponentialIndex(value, dp.Scale())) - int(dp.Positive().Offset())
+				expected[index]++
+				assert.Less(t, math.Pow(base, float64(int(dp.Positive().Offset())+index)), value)
+				assert.GreaterOrEqual(t, math.Pow(base, float64(int(dp.Positive().Offset())+index+1)), value)
+			}
+		}
+		for i := 0; i < dp.Positive().BucketCounts().Len(); i++ {
+			assert.Equal(t, expected[i], dp.Positive().BucketCounts().At(i))
+		}
+		var negativeCount uint64
+		for i := 0; i < dp.Negative().BucketCounts().Len(); i++ {
+			negativeCount += dp.Negative().BucketCounts().At(i)
+		}
+		assert.Equal(t, uint64(1), negativeCount)
+	})
+}
+
+func TestValidateGaugeAggregationSummaryOutput(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{"jitter": {"p90", "max", "p50"}},
+			MetricsOptions: map[string]MetricOptions{
+				"jitter": {GaugeOutput: GaugeOutputSummary},
+			},
+			RelativeAccuracy: 0.01,
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	values := make([]float64, 0, 100)
+	for i := 1; i <= 100; i++ {
+		values = append(values, float64(i))
+	}
+	var mainMetrics pmetric.Metrics = CreateDoubleGaugeArgument("jitter", startTS, values)
+
+	t.Run("validate gauge is emitted as a single summary", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 1, scope.Metrics().Len())
+		metric := scope.Metrics().At(0)
+		assert.Equal(t, pmetric.MetricTypeSummary, metric.Type())
+		assert.Equal(t, "jitter", metric.Name())
+
+		dp := metric.Summary().DataPoints().At(0)
+		assert.Equal(t, startTS, dp.StartTimestamp())
+		assert.Equal(t, uint64(100), dp.Count())
+		assert.Equal(t, 5050.0, dp.Sum())
+
+		expected := []struct {
+			quantile float64
+			value    float64
+		}{{0, 1}, {0.5, 50}, {0.9, 90}, {1, 100}}
+		assert.Equal(t, len(expected), dp.QuantileValues().Len())
+		for i, quantile := range expected {
+			assert.Equal(t, quantile.quantile, dp.QuantileValues().At(i).Quantile())
+			assert.InEpsilon(t, quantile.value, dp.QuantileValues().At(i).Value(), 0.01)
+		}
+	})
+
+	t.Run("validate the count and sum are running totals across windows", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, CreateDoubleGaugeArgument("jitter", startTS, []float64{10, 20}))
+
+		assert.NoError(t, error)
+		dp := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Summary().DataPoints().At(0)
+		assert.Equal(t, startTS, dp.StartTimestamp())
+		assert.Equal(t, uint64(102), dp.Count())
+		assert.Equal(t, 5080.0, dp.Sum())
+		assert.Equal(t, 10.0, dp.QuantileValues().At(0).Value())
+		assert.Equal(t, 20.0, dp.QuantileValues().At(dp.QuantileValues().Len()-1).Value())
+	})
+}
+
+func TestValidateGaugeAggregationStatisticAttribute(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics:  map[string][]Statistic{"speed": {"max", "min", "count", "p50"}},
+			RelativeAccuracy:   0.01,
+			StatisticNaming:    StatisticNamingAttribute,
+			StatisticAttribute: "stat",
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	var mainMetrics pmetric.Metrics = CreateIntGaugeArgument("speed", startTS, []int64{3, 9, 5})
+	SetDatapointAttributes(mainMetrics, []map[string]any{{"lane": "1"}, {"lane": "1"}, {"lane": "1"}})
+
+	t.Run("validate statistics are datapoints of the original metric", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 1, scope.Metrics().Len())
+		metric := scope.Metrics().At(0)
+		assert.Equal(t, "speed", metric.Name())
+		assert.Equal(t, pmetric.MetricTypeGauge, metric.Type())
+
+		expected := map[string]float64{"max": 9, "min": 3, "count": 3, "p50": 5}
+		assert.Equal(t, len(expected), metric.Gauge().DataPoints().Len())
+		for l := 0; l < metric.Gauge().DataPoints().Len(); l++ {
+			dp := metric.Gauge().DataPoints().At(l)
+			lane, ok := dp.Attributes().Get("lane")
+			assert.True(t, ok)
+			assert.Equal(t, "1", lane.Str())
+			stat, ok := dp.Attributes().Get("stat")
+			assert.True(t, ok)
+			value, ok := expected[stat.Str()]
+			assert.True(t, ok, stat.Str())
+			delete(expected, stat.Str())
+			if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+				assert.Equal(t, int64(value), dp.IntValue())
+			} else {
+				assert.InEpsilon(t, value, dp.DoubleValue(), 0.01)
+			}
+		}
+		assert.Empty(t, expected)
+	})
+
+	t.Run("validate the series of a scope share the original metric", func(t *testing.T) {
+		laneMetrics := CreateIntGaugeArgument("speed", startTS, []int64{3, 9, 5})
+		SetDatapointAttributes(laneMetrics, []map[string]any{{"lane": "1"}, {"lane": "2"}, {"lane": "1"}})
+
+		finalMetrics, error := processor.ProcessMetrics(nil, laneMetrics)
+
+		assert.NoError(t, error)
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 1, scope.Metrics().Len())
+		metric := scope.Metrics().At(0)
+		assert.Equal(t, "speed", metric.Name())
+
+		expected := map[string]float64{
+			"1|max": 5, "1|min": 3, "1|count": 2,
+			"2|max": 9, "2|min": 9, "2|count": 1,
+		}
+		wasChecked := 0
+		for l := 0; l < metric.Gauge().DataPoints().Len(); l++ {
+			dp := metric.Gauge().DataPoints().At(l)
+			lane, _ := dp.Attributes().Get("lane")
+			stat, _ := dp.Attributes().Get("stat")
+			if value, ok := expected[lane.Str()+"|"+stat.Str()]; ok {
+				assert.Equal(t, int64(value), dp.IntValue(), lane.Str()+"|"+stat.Str())
+				wasChecked++
+			}
+		}
+		assert.Equal(t, 8, metric.Gauge().DataPoints().Len())
+		assert.Equal(t, len(expected), wasChecked)
+	})
+}
+
+func TestValidateGaugeAggregationNameTemplate(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{
+				"speed":       {"max", "min", "p99.9"},
+				"temperature": {"max", "min"},
+			},
+			MetricsOptions: map[string]MetricOptions{
+				"speed":       {StatisticNames: map[string]string{"max": "top_speed"}},
+				"temperature": {NameTemplate: "{name}_{stat}_{unit}"},
+			},
+			RelativeAccuracy: 0.01,
+			NameTemplate:     "{name}.{stat}",
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	var mainMetrics pmetric.Metrics = CreateIntGaugeArgument("speed", startTS, []int64{3, 9, 5})
+	temperatureMetrics := CreateIntGaugeArgument("temperature", startTS, []int64{20, 25})
+	temperatureMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).SetUnit("Cel")
+	temperatureMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).MoveTo(
+		mainMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().AppendEmpty())
+
+	t.Run("validate names follow the templates and overrides", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		expected := map[string]bool{
+			"top_speed":           true,
+			"speed.min":           true,
+			"speed.p99_9":         true,
+			"temperature_max_Cel": true,
+			"temperature_min_Cel": true,
+		}
+
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, len(expected), scope.Metrics().Len())
+		for k := 0; k < scope.Metrics().Len(); k++ {
+			name := scope.Metrics().At(k).Name()
+			assert.True(t, expected[name], name)
+			delete(expected, name)
+		}
+		assert.Empty(t, expected)
+	})
+}
+
+func TestValidateGaugeAggregationRules(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logger := zap.New(core)
+	var rules []ProcessedGaugeAggregationRule
+	for _, rule := range []GaugeAggregationRule{
+		{Glob: "audio.dsp.*.level", Statistics: []string{"max"}},
+		{Regex: `audio\.dsp\..*`, Statistics: []string{"min"}},
+	} {
+		processedRule, err := ProcessGaugeAggregationRule(rule)
+		assert.NoError(t, err)
+		rules = append(rules, processedRule)
+	}
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics:     map[string][]Statistic{"audio.dsp.left.level": {"sum"}},
+			GaugeAggregationRules: rules,
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	var mainMetrics pmetric.Metrics = pmetric.NewMetrics()
+	scopeMetrics := mainMetrics.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	for _, name := range []string{"audio.dsp.left.level", "Audio.DSP.Right.Level", "audio.dsp.gain", "battery"} {
+		metricArgument := CreateIntGaugeArgument(name, startTS, []int64{-3, 5})
+		metricArgument.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).MoveTo(scopeMetrics.Metrics().AppendEmpty())
+	}
+	var nextMetrics pmetric.Metrics = pmetric.NewMetrics()
+	mainMetrics.CopyTo(nextMetrics)
+
+	t.Run("validate exact names take precedence over the rules in order", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		expected := map[string]int64{
+			"audio.dsp.left.level_gauge_sum":  2,
+			"Audio.DSP.Right.Level_gauge_max": 5,
+			"audio.dsp.gain_gauge_min":        -3,
+			"battery_gauge_abs_max":           5,
+			"battery_gauge_abs_min":           -3,
+		}
+
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, len(expected), scope.Metrics().Len())
+		for k := 0; k < scope.Metrics().Len(); k++ {
+			metric := scope.Metrics().At(k)
+			value, ok := expected[metric.Name()]
+			assert.True(t, ok, metric.Name())
+			var wasChecked bool = false
+			ValidateIntGauge(t, metric, &wasChecked, value, startTS)
+			assert.True(t, wasChecked)
+		}
+	})
+	t.Run("validate the rules are only matched once for each gauge", func(t *testing.T) {
+		_, error := processor.ProcessMetrics(nil, nextMetrics)
+
+		assert.NoError(t, error)
+		assert.Equal(t, 2, logs.FilterMessage("Gauge matched an aggregation rule").Len())
+	})
+}
+
+func TestValidateGaugeAggregationDefaultStatistics(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	t.Run("validate unlisted gauges use the configured default", func(t *testing.T) {
+		var processor *ReduceResolution = &ReduceResolution{
+			Logger: logger,
+			Config: ProcessedConfig{
+				MetricsStatistics:      map[string][]Statistic{"speed": {"max"}},
+				DefaultGaugeStatistics: []Statistic{"last"},
+			},
+		}
+		mainMetrics := CreateIntGaugeArgument("battery", startTS, []int64{80, 75})
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 1, scope.Metrics().Len())
+		assert.Equal(t, "battery_gauge_last", scope.Metrics().At(0).Name())
+		assert.Equal(t, int64(75), scope.Metrics().At(0).Gauge().DataPoints().At(0).IntValue())
+	})
+
+	t.Run("validate unlisted gauges are dropped with an empty default", func(t *testing.T) {
+		var processor *ReduceResolution = &ReduceResolution{
+			Logger: logger,
+			Config: ProcessedConfig{
+				MetricsStatistics:      map[string][]Statistic{"speed": {"max"}},
+				DefaultGaugeStatistics: []Statistic{},
+			},
+		}
+		mainMetrics := CreateIntGaugeArgument("battery", startTS, []int64{80, 75})
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		assert.Equal(t, 0, finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().Len())
+	})
+
+	t.Run("validate unlisted gauges are forwarded unchanged with passthrough", func(t *testing.T) {
+		var processor *ReduceResolution = &ReduceResolution{
+			Logger: logger,
+			Config: ProcessedConfig{
+				MetricsStatistics:      map[string][]Statistic{"speed": {"max"}},
+				DefaultGaugeStatistics: []Statistic{StatisticPassthrough},
+			},
+		}
+		mainMetrics := CreateIntGaugeArgument("battery", startTS, []int64{80, 75})
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 1, scope.Metrics().Len())
+		metric := scope.Metrics().At(0)
+		assert.Equal(t, "battery", metric.Name())
+		assert.Equal(t, 2, metric.Gauge().DataPoints().Len())
+		assert.Equal(t, int64(80), metric.Gauge().DataPoints().At(0).IntValue())
+		assert.Equal(t, int64(75), metric.Gauge().DataPoints().At(1).IntValue())
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestValidateHistogramAggregation(t *testing.T) {
@@ -301,3 +302,622 @@ func TestValidateHistogramSameScopeAggregationWithDifferentBounds(t *testing.T)
 		}
 	})
 }
+
+func TestValidateHistogramAggregationCumulativeResets(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	rebootTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 35, 0, time.UTC))
+	histograms := []HistogramArg{
+		{
+			"testhistogram",
+			startTS,
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC)),
+			true,
+			[]float64{0.0, 5.0, 10.0},
+			[]HistogramValue{
+				{3, 14.0, 6.0, 2.0, []uint64{0, 2, 1, 0}},
+			},
+		},
+		{
+			"testhistogram",
+			rebootTS,
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 40, 0, time.UTC)),
+			true,
+			[]float64{0.0, 5.0, 10.0},
+			[]HistogramValue{
+				{1, 12.0, 12.0, 12.0, []uint64{0, 0, 0, 1}},
+			},
+		},
+	}
+
+	for _, test := range []struct {
+		name    string
+		resets  string
+		value   HistogramValue
+		startTS pcommon.Timestamp
+	}{
+		{"validate the observations before a restart are accumulated", CumulativeResetsAccumulate, HistogramValue{4, 26.0, 12.0, 2.0, []uint64{0, 2, 1, 1}}, startTS},
+		{"validate a restart starts a new series", CumulativeResetsRestart, HistogramValue{1, 12.0, 12.0, 12.0, []uint64{0, 0, 0, 1}}, rebootTS},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var processor *ReduceResolution = &ReduceResolution{
+				Logger: logger,
+				Config: ProcessedConfig{CumulativeResets: test.resets},
+			}
+			var mainMetrics pmetric.Metrics = CreateArgument(
+				MetricArg{
+					[]ResourceMetricsArg{
+						{
+							[]ScopeArg{
+								{
+									"testscope",
+									"1.0",
+									[]GaugeArg[float64]{},
+									[]GaugeArg[int64]{},
+									[]CounterArg[float64]{},
+									[]CounterArg[int64]{},
+									histograms,
+								},
+							},
+						},
+					},
+				},
+			)
+
+			finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+			assert.NoError(t, error)
+			var histogram bool = false
+			scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+			assert.Equal(t, 1, scope.Metrics().Len())
+			ValidateHistogram(t, scope.Metrics().At(0), &histogram, true, []float64{0.0, 5.0, 10.0}, test.value, test.startTS)
+			assert.True(t, histogram)
+		})
+	}
+}
+
+func TestValidateHistogramStatistics(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			HistogramStatistics: map[string][]Statistic{
+				"latency": {StatisticCount, StatisticMean, "p50", "p90"},
+			},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	value := HistogramValue{5, 30.0, 12.0, 2.0, []uint64{0, 3, 1, 1}}
+	var mainMetrics pmetric.Metrics = CreateArgument(
+		MetricArg{
+			[]ResourceMetricsArg{
+				{
+					[]ScopeArg{
+						{
+							"testscope",
+							"1.0",
+							[]GaugeArg[float64]{},
+							[]GaugeArg[int64]{},
+							[]CounterArg[float64]{},
+							[]CounterArg[int64]{},
+							[]HistogramArg{
+								{
+									"latency",
+									startTS,
+									pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC)),
+									false,
+									[]float64{0.0, 5.0, 10.0},
+									[]HistogramValue{value},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	)
+
+	t.Run("validate the statistics are emitted next to the histogram", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 5, scope.Metrics().Len())
+		var histogram, count, mean, p50, p90 bool = false, false, false, false, false
+		for i := 0; i < scope.Metrics().Len(); i++ {
+			metric := scope.Metrics().At(i)
+			switch metric.Name() {
+			case "latency":
+				ValidateHistogram(t, metric, &histogram, false, []float64{0.0, 5.0, 10.0}, value, startTS)
+			case "latency_count":
+				ValidateIntGauge(t, metric, &count, 5, startTS)
+			case "latency_mean":
+				ValidateDoubleGauge(t, metric, &mean, 6.0, startTS)
+			case "latency_p50":
+				ValidateDoubleGauge(t, metric, &p50, 4.5, startTS)
+			case "latency_p90":
+				ValidateDoubleGauge(t, metric, &p90, 11.0, startTS)
+			default:
+				assert.Fail(t, "unexpected metric", metric.Name())
+			}
+		}
+		assert.True(t, histogram)
+		assert.True(t, count)
+		assert.True(t, mean)
+		assert.True(t, p50)
+		assert.True(t, p90)
+	})
+
+	t.Run("validate the histogram is dropped when only its statistics are emitted", func(t *testing.T) {
+		processor.Config.MetricsOptions = map[string]MetricOptions{
+			"latency": {HistogramOutput: HistogramOutputStatistics},
+		}
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 4, scope.Metrics().Len())
+		for i := 0; i < scope.Metrics().Len(); i++ {
+			assert.Equal(t, pmetric.MetricTypeGauge, scope.Metrics().At(i).Type())
+		}
+	})
+}
+
+func TestValidateHistogramStatisticsCumulative(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			HistogramStatistics: map[string][]Statistic{
+				"latency": {StatisticCount, StatisticMean, StatisticMin, "p50"},
+			},
+			MetricsOptions: map[string]MetricOptions{
+				"latency": {HistogramOutput: HistogramOutputStatistics},
+			},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+
+	for _, window := range []struct {
+		name     string
+		ts       pcommon.Timestamp
+		value    HistogramValue
+		expected map[string]float64
+	}{
+		{
+			"validate the first window has the statistics of the whole series",
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC)),
+			HistogramValue{5, 30.0, 12.0, 2.0, []uint64{0, 3, 1, 1}},
+			map[string]float64{"latency_count": 5, "latency_mean": 6, "latency_min": 2, "latency_p50": 4.5},
+		},
+		{
+			"validate the next window has the statistics of its own observations",
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 1, 30, 0, time.UTC)),
+			HistogramValue{8, 60.0, 14.0, 2.0, []uint64{0, 4, 2, 2}},
+			map[string]float64{"latency_count": 3, "latency_mean": 10, "latency_p50": 7.5},
+		},
+	} {
+		t.Run(window.name, func(t *testing.T) {
+			metrics := CreateArgument(
+				MetricArg{
+					[]ResourceMetricsArg{
+						{
+							[]ScopeArg{
+								{
+									"testscope",
+									"1.0",
+									[]GaugeArg[float64]{},
+									[]GaugeArg[int64]{},
+									[]CounterArg[float64]{},
+									[]CounterArg[int64]{},
+									[]HistogramArg{
+										{"latency", startTS, window.ts, true, []float64{0.0, 5.0, 10.0}, []HistogramValue{window.value}},
+									},
+								},
+							},
+						},
+					},
+				},
+			)
+			finalMetrics, error := processor.ProcessMetrics(nil, metrics)
+
+			assert.NoError(t, error)
+			scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+			assert.Equal(t, len(window.expected), scope.Metrics().Len())
+			for i := 0; i < scope.Metrics().Len(); i++ {
+				metric := scope.Metrics().At(i)
+				expected, ok := window.expected[metric.Name()]
+				assert.True(t, ok, metric.Name())
+				dp := metric.Gauge().DataPoints().At(0)
+				if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+					assert.Equal(t, int64(expected), dp.IntValue(), metric.Name())
+				} else {
+					assert.InDelta(t, expected, dp.DoubleValue(), 1e-9, metric.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestValidateHistogramStatisticsWithoutMinMax(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			HistogramStatistics: map[string][]Statistic{
+				"latency": {StatisticCount, StatisticMin, StatisticMax, "p5", "p50", "p99"},
+			},
+			MetricsOptions: map[string]MetricOptions{
+				"latency": {HistogramOutput: HistogramOutputStatistics},
+			},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	var mainMetrics pmetric.Metrics = CreateArgument(
+		MetricArg{
+			[]ResourceMetricsArg{
+				{
+					[]ScopeArg{
+						{
+							"testscope",
+							"1.0",
+							[]GaugeArg[float64]{},
+							[]GaugeArg[int64]{},
+							[]CounterArg[float64]{},
+							[]CounterArg[int64]{},
+							[]HistogramArg{
+								{
+									"latency",
+									startTS,
+									pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC)),
+									false,
+									[]float64{1.0, 2.0, 4.0},
+									[]HistogramValue{{10, 25.0, 0, 0, []uint64{1, 4, 4, 1}}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	)
+	// Like the histograms converted from Prometheus, which have no minimum and maximum
+	dp := mainMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0)
+	dp.RemoveMin()
+	dp.RemoveMax()
+
+	t.Run("validate the percentiles use the bounds of the buckets", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 4, scope.Metrics().Len())
+		var count, p5, p50, p99 bool = false, false, false, false
+		for i := 0; i < scope.Metrics().Len(); i++ {
+			metric := scope.Metrics().At(i)
+			switch metric.Name() {
+			case "latency_count":
+				ValidateIntGauge(t, metric, &count, 10, startTS)
+			case "latency_p5":
+				ValidateDoubleGauge(t, metric, &p5, 1.0, startTS)
+			case "latency_p50":
+				ValidateDoubleGauge(t, metric, &p50, 2.0, startTS)
+			case "latency_p99":
+				ValidateDoubleGauge(t, metric, &p99, 4.0, startTS)
+			default:
+				assert.Fail(t, "unexpected metric", metric.Name())
+			}
+		}
+		assert.True(t, count)
+		assert.True(t, p5)
+		assert.True(t, p50)
+		assert.True(t, p99)
+	})
+}
+
+func TestValidateHistogramBoundsMismatch(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	var mainMetrics pmetric.Metrics = CreateArgument(
+		MetricArg{
+			[]ResourceMetricsArg{
+				{
+					[]ScopeArg{
+						{
+							"testscope",
+							"1.0",
+							[]GaugeArg[float64]{},
+							[]GaugeArg[int64]{},
+							[]CounterArg[float64]{},
+							[]CounterArg[int64]{},
+							[]HistogramArg{
+								{
+									"testhistogram",
+									startTS,
+									pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC)),
+									false,
+									[]float64{0.0, 10.0, 20.0},
+									[]HistogramValue{
+										{4, 40.0, 18.0, 2.0, []uint64{0, 2, 2, 0}},
+									},
+								},
+								{
+									"testhistogram",
+									startTS,
+									pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 40, 0, time.UTC)),
+									false,
+									[]float64{0.0, 5.0, 10.0},
+									[]HistogramValue{
+										{3, 27.0, 15.0, 3.0, []uint64{0, 1, 1, 1}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	)
+
+	for _, test := range []struct {
+		name     string
+		mismatch string
+		bounds   []float64
+		value    HistogramValue
+	}{
+		{"validate the second datapoint is dropped", HistogramBoundsMismatchDrop, []float64{0.0, 10.0, 20.0}, HistogramValue{4, 40.0, 18.0, 2.0, []uint64{0, 2, 2, 0}}},
+		{"validate the common bounds merge the buckets", HistogramBoundsMismatchCommon, []float64{0.0, 10.0}, HistogramValue{7, 67.0, 18.0, 2.0, []uint64{0, 4, 3}}},
+		{"validate the union of the bounds splits the buckets", HistogramBoundsMismatchUnion, []float64{0.0, 5.0, 10.0, 20.0}, HistogramValue{7, 67.0, 18.0, 2.0, []uint64{0, 2, 2, 3, 0}}},
+		{"validate the configured bounds are used", HistogramBoundsMismatchConfigured, []float64{0.0, 100.0}, HistogramValue{7, 67.0, 18.0, 2.0, []uint64{0, 7, 0}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var processor *ReduceResolution = &ReduceResolution{
+				Logger: logger,
+				Config: ProcessedConfig{
+					MetricsOptions: map[string]MetricOptions{
+						"testhistogram": {HistogramBoundsMismatch: test.mismatch, ExplicitBounds: []float64{0.0, 100.0}},
+					},
+				},
+			}
+			finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+			assert.NoError(t, error)
+			var histogram bool = false
+			scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+			assert.Equal(t, 1, scope.Metrics().Len())
+			ValidateHistogram(t, scope.Metrics().At(0), &histogram, false, test.bounds, test.value, startTS)
+			assert.True(t, histogram)
+		})
+	}
+}
+
+func TestValidateHistogramBoundsMismatchWithoutMinMax(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsOptions: map[string]MetricOptions{
+				"testhistogram": {HistogramBoundsMismatch: HistogramBoundsMismatchUnion},
+			},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	var mainMetrics pmetric.Metrics = CreateArgument(
+		MetricArg{
+			[]ResourceMetricsArg{
+				{
+					[]ScopeArg{
+						{
+							"testscope",
+							"1.0",
+							[]GaugeArg[float64]{},
+							[]GaugeArg[int64]{},
+							[]CounterArg[float64]{},
+							[]CounterArg[int64]{},
+							[]HistogramArg{
+								{
+									"testhistogram",
+									startTS,
+									pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC)),
+									false,
+									[]float64{0.0, 10.0, 20.0},
+									[]HistogramValue{
+										{5, 70.0, 0, 0, []uint64{0, 2, 2, 1}},
+									},
+								},
+								{
+									"testhistogram",
+									startTS,
+									pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 40, 0, time.UTC)),
+									false,
+									[]float64{0.0, 5.0, 10.0, 30.0},
+									[]HistogramValue{
+										{4, 80.0, 0, 0, []uint64{0, 1, 1, 1, 1}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	)
+	// Like the histograms converted from Prometheus, which have no minimum and maximum
+	metrics := mainMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for k := 0; k < metrics.Len(); k++ {
+		metrics.At(k).Histogram().DataPoints().At(0).RemoveMin()
+		metrics.At(k).Histogram().DataPoints().At(0).RemoveMax()
+	}
+
+	t.Run("validate the unbounded last buckets stay above their lower bound", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		var histogram bool = false
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 1, scope.Metrics().Len())
+		ValidateHistogram(t, scope.Metrics().At(0), &histogram, false, []float64{0.0, 5.0, 10.0, 20.0, 30.0}, HistogramValue{9, 150.0, 0, 0, []uint64{0, 2, 2, 3, 1, 1}}, startTS)
+		assert.True(t, histogram)
+		assert.False(t, scope.Metrics().At(0).Histogram().DataPoints().At(0).HasMin())
+		assert.False(t, scope.Metrics().At(0).Histogram().DataPoints().At(0).HasMax())
+	})
+}
+
+func TestValidateExponentialHistogramAggregationDelta(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	mainMetrics := CreateExponentialHistogramArgument([]ExponentialHistogramArg{
+		{
+			"testhistogram",
+			startTS,
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC)),
+			false,
+			[]ExponentialHistogramValue{
+				{3, 3.5, 1.9, 0.0, 1, 1, ExponentialBuckets{0, []uint64{1, 1}}, ExponentialBuckets{}},
+			},
+		},
+		{
+			"testhistogram",
+			startTS,
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 40, 0, time.UTC)),
+			false,
+			[]ExponentialHistogramValue{
+				{3, 4.5, 3.5, -1.5, 0, 0, ExponentialBuckets{1, []uint64{2}}, ExponentialBuckets{0, []uint64{1}}},
+			},
+		},
+	})
+
+	t.Run("validate the buckets are merged at the lowest scale", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		var histogram bool = false
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 1, scope.Metrics().Len())
+		ValidateExponentialHistogram(t, scope.Metrics().At(0), &histogram, false,
+			ExponentialHistogramValue{6, 8.0, 3.5, -1.5, 0, 1, ExponentialBuckets{0, []uint64{2, 2}}, ExponentialBuckets{0, []uint64{1}}}, startTS)
+		assert.True(t, histogram)
+	})
+}
+
+func TestValidateExponentialHistogramAggregationCumulativeResets(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	rebootTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 35, 0, time.UTC))
+	histograms := []ExponentialHistogramArg{
+		{
+			"testhistogram",
+			startTS,
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC)),
+			true,
+			[]ExponentialHistogramValue{
+				{3, 4.5, 1.8, 1.2, 0, 0, ExponentialBuckets{0, []uint64{3}}, ExponentialBuckets{}},
+			},
+		},
+		{
+			"testhistogram",
+			rebootTS,
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 40, 0, time.UTC)),
+			true,
+			[]ExponentialHistogramValue{
+				{1, 3.0, 3.0, 3.0, 0, 0, ExponentialBuckets{1, []uint64{1}}, ExponentialBuckets{}},
+			},
+		},
+	}
+
+	for _, test := range []struct {
+		name    string
+		resets  string
+		value   ExponentialHistogramValue
+		startTS pcommon.Timestamp
+	}{
+		{"validate the observations before a restart are accumulated", CumulativeResetsAccumulate,
+			ExponentialHistogramValue{4, 7.5, 3.0, 1.2, 0, 0, ExponentialBuckets{0, []uint64{3, 1}}, ExponentialBuckets{}}, startTS},
+		{"validate a restart starts a new series", CumulativeResetsRestart,
+			ExponentialHistogramValue{1, 3.0, 3.0, 3.0, 0, 0, ExponentialBuckets{1, []uint64{1}}, ExponentialBuckets{}}, rebootTS},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var processor *ReduceResolution = &ReduceResolution{
+				Logger: logger,
+				Config: ProcessedConfig{CumulativeResets: test.resets},
+			}
+			finalMetrics, error := processor.ProcessMetrics(nil, CreateExponentialHistogramArgument(histograms))
+
+			assert.NoError(t, error)
+			var histogram bool = false
+			scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+			assert.Equal(t, 1, scope.Metrics().Len())
+			ValidateExponentialHistogram(t, scope.Metrics().At(0), &histogram, true, test.value, test.startTS)
+			assert.True(t, histogram)
+		})
+	}
+}
+
+func TestValidateHistogramCoarseBuckets(t *testing.T) {
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	var mainMetrics pmetric.Metrics = CreateArgument(
+		MetricArg{
+			[]ResourceMetricsArg{
+				{
+					[]ScopeArg{
+						{
+							"testscope",
+							"1.0",
+							[]GaugeArg[float64]{},
+							[]GaugeArg[int64]{},
+							[]CounterArg[float64]{},
+							[]CounterArg[int64]{},
+							[]HistogramArg{
+								{
+									"testhistogram",
+									startTS,
+									pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC)),
+									false,
+									[]float64{0.0, 5.0, 10.0, 20.0},
+									[]HistogramValue{
+										{10, 150.0, 30.0, 2.0, []uint64{0, 1, 2, 3, 4}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	)
+
+	for _, test := range []struct {
+		name     string
+		options  MetricOptions
+		bounds   []float64
+		buckets  []uint64
+		warnings int
+	}{
+		{"validate every two adjacent buckets are merged", MetricOptions{MergeBuckets: 2}, []float64{5.0, 20.0}, []uint64{1, 5, 4}, 0},
+		{"validate the buckets are merged into the coarse bounds", MetricOptions{CoarseBounds: []float64{0.0, 10.0}}, []float64{0.0, 10.0}, []uint64{0, 3, 7}, 0},
+		{"validate the coarse bounds that were not received are dropped with a warning", MetricOptions{CoarseBounds: []float64{0.0, 7.0, 10.0, 50.0}}, []float64{0.0, 10.0}, []uint64{0, 3, 7}, 1},
+		{"validate a single bucket is left when none of the coarse bounds were received", MetricOptions{CoarseBounds: []float64{7.0, 50.0}}, []float64{}, []uint64{10}, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			core, logs := observer.New(zap.WarnLevel)
+			var processor *ReduceResolution = &ReduceResolution{
+				Logger: zap.New(core),
+				Config: ProcessedConfig{
+					MetricsOptions: map[string]MetricOptions{"testhistogram": test.options},
+				},
+			}
+			finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+			assert.NoError(t, error)
+			var histogram bool = false
+			scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+			assert.Equal(t, 1, scope.Metrics().Len())
+			ValidateHistogram(t, scope.Metrics().At(0), &histogram, false, test.bounds, HistogramValue{10, 150.0, 30.0, 2.0, test.buckets}, startTS)
+			assert.True(t, histogram)
+			assert.Equal(t, test.warnings, logs.Len())
+		})
+	}
+}
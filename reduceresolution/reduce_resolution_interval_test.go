@@ -0,0 +1,83 @@
+// Copyright (C) 2025 Bang & Olufsen A/S, Denmark
+//
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package reduceresolution
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.uber.org/zap"
+)
+
+func TestValidateIntervalAggregatesAcrossCalls(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	sink := new(consumertest.MetricsSink)
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger:       logger,
+		Config:       ProcessedConfig{MetricsStatistics: map[string][]Statistic{}, Interval: time.Hour},
+		nextConsumer: sink,
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	t.Run("validate nothing is forwarded before the flush", func(t *testing.T) {
+		_, err := processor.ProcessMetrics(context.Background(), CreateIntGaugeArgument("testmetric", startTS, []int64{3}))
+		assert.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+		_, err = processor.ProcessMetrics(context.Background(), CreateIntGaugeArgument("testmetric", startTS, []int64{-7, 5}))
+		assert.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+		assert.Equal(t, 0, len(sink.AllMetrics()))
+	})
+
+	t.Run("validate the flush contains both calls", func(t *testing.T) {
+		assert.NoError(t, processor.Flush(context.Background()))
+		assert.Equal(t, 1, len(sink.AllMetrics()))
+
+		var max bool = false
+		var min bool = false
+		finalMetrics := sink.AllMetrics()[0]
+		assert.Equal(t, 1, finalMetrics.ResourceMetrics().Len())
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		for k := 0; k < scope.Metrics().Len(); k++ {
+			metric := scope.Metrics().At(k)
+			switch metric.Name() {
+			case "testmetric_gauge_abs_max":
+				ValidateIntGauge(t, metric, &max, -7, startTS)
+			case "testmetric_gauge_abs_min":
+				ValidateIntGauge(t, metric, &min, 3, startTS)
+			}
+		}
+		assert.True(t, max)
+		assert.True(t, min)
+	})
+
+	t.Run("validate an empty window is not forwarded", func(t *testing.T) {
+		assert.NoError(t, processor.Flush(context.Background()))
+		assert.Equal(t, 1, len(sink.AllMetrics()))
+	})
+}
+
+func TestValidateIntervalShutdownFlushes(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	sink := new(consumertest.MetricsSink)
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger:       logger,
+		Config:       ProcessedConfig{MetricsStatistics: map[string][]Statistic{}, Interval: time.Hour},
+		nextConsumer: sink,
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	t.Run("validate pending aggregates are sent on shutdown", func(t *testing.T) {
+		assert.NoError(t, processor.Start(context.Background(), nil))
+		_, err := processor.ProcessMetrics(context.Background(), CreateIntGaugeArgument("testmetric", startTS, []int64{3}))
+		assert.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+		assert.NoError(t, processor.Shutdown(context.Background()))
+		assert.Equal(t, 1, len(sink.AllMetrics()))
+		assert.Equal(t, 2, sink.DataPointCount())
+	})
+}
@@ -0,0 +1,186 @@
+// Copyright (C) 2025 Bang & Olufsen A/S, Denmark
+//
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package reduceresolution
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+func TestValidateGaugeAggregationDifferentResources(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{MetricsStatistics: map[string][]Statistic{}},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	var mainMetrics pmetric.Metrics = pmetric.NewMetrics()
+	for _, device := range []struct {
+		id     string
+		values []int64
+	}{
+		{"device-a", []int64{3, 5}},
+		{"device-b", []int64{-1}},
+		{"device-a", []int64{4}},
+	} {
+		deviceMetrics := CreateIntGaugeArgument("testmetric", startTS, device.values)
+		resourceMetric := deviceMetrics.ResourceMetrics().At(0)
+		resourceMetric.Resource().Attributes().PutStr("device.id", device.id)
+		resourceMetric.SetSchemaUrl("https://opentelemetry.io/schemas/1.21.0")
+		resourceMetric.MoveTo(mainMetrics.ResourceMetrics().AppendEmpty())
+	}
+
+	t.Run("validate each resource is reduced on its own", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		assert.Equal(t, 2, finalMetrics.ResourceMetrics().Len())
+
+		expected := map[string][2]int64{
+			"device-a": {5, 3},
+			"device-b": {-1, -1},
+		}
+		for i := 0; i < finalMetrics.ResourceMetrics().Len(); i++ {
+			resourceMetric := finalMetrics.ResourceMetrics().At(i)
+			assert.Equal(t, "https://opentelemetry.io/schemas/1.21.0", resourceMetric.SchemaUrl())
+			deviceID, ok := resourceMetric.Resource().Attributes().Get("device.id")
+			assert.True(t, ok)
+			values, ok := expected[deviceID.Str()]
+			assert.True(t, ok)
+			delete(expected, deviceID.Str())
+
+			var max bool = false
+			var min bool = false
+			assert.Equal(t, 1, resourceMetric.ScopeMetrics().Len())
+			scope := resourceMetric.ScopeMetrics().At(0)
+			for k := 0; k < scope.Metrics().Len(); k++ {
+				metric := scope.Metrics().At(k)
+				switch metric.Name() {
+				case "testmetric_gauge_abs_max":
+					ValidateIntGauge(t, metric, &max, values[0], startTS)
+				case "testmetric_gauge_abs_min":
+					ValidateIntGauge(t, metric, &min, values[1], startTS)
+				}
+			}
+			assert.True(t, max)
+			assert.True(t, min)
+		}
+		assert.Empty(t, expected)
+	})
+}
+
+func TestValidateCounterRollupByResourceAttributes(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics:        map[string][]Statistic{},
+			RollupResourceAttributes: []string{"device.model", "firmware.version"},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	ts := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC))
+
+	var mainMetrics pmetric.Metrics = pmetric.NewMetrics()
+	for _, device := range []struct {
+		attributes map[string]any
+		value      int64
+	}{
+		{map[string]any{"device.id": "a", "device.model": "x", "firmware.version": "1.0"}, 3},
+		{map[string]any{"device.id": "b", "device.model": "x", "firmware.version": "1.0"}, 4},
+		{map[string]any{"device.id": "c", "device.model": "y"}, 10},
+		{map[string]any{"device.id": "d"}, 20},
+	} {
+		deviceMetrics := CreateIntCounterArgument("testmetric", startTS, ts, true, []int64{device.value})
+		resourceMetric := deviceMetrics.ResourceMetrics().At(0)
+		_ = resourceMetric.Resource().Attributes().FromRaw(device.attributes)
+		resourceMetric.MoveTo(mainMetrics.ResourceMetrics().AppendEmpty())
+	}
+
+	t.Run("validate devices and cohorts are emitted", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		assert.Equal(t, 6, finalMetrics.ResourceMetrics().Len())
+
+		expected := map[string]int64{
+			"device.id=a,device.model=x,firmware.version=1.0": 3,
+			"device.id=b,device.model=x,firmware.version=1.0": 4,
+			"device.id=c,device.model=y":                      10,
+			"device.id=d":                                     20,
+			"device.model=x,firmware.version=1.0":             7,
+			"device.model=y":                                  10,
+		}
+		for i := 0; i < finalMetrics.ResourceMetrics().Len(); i++ {
+			resourceMetric := finalMetrics.ResourceMetrics().At(i)
+			resourceKey := CreateAttributesKey(resourceMetric.Resource().Attributes())
+			value, ok := expected[resourceKey]
+			assert.True(t, ok, resourceKey)
+			delete(expected, resourceKey)
+
+			var counter bool = false
+			scope := resourceMetric.ScopeMetrics().At(0)
+			assert.Equal(t, 1, scope.Metrics().Len())
+			ValidateIntCounter(t, scope.Metrics().At(0), &counter, true, true, value, startTS)
+			assert.True(t, counter)
+		}
+		assert.Empty(t, expected)
+	})
+}
+
+func TestValidateGaugeRollupTimeWeightedAverage(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics:        map[string][]Statistic{"volume": {"twa"}},
+			RollupResourceAttributes: []string{"device.model"},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	for window := 0; window < 2; window++ {
+		t.Run(fmt.Sprintf("validate the devices keep their own last value in window %d", window), func(t *testing.T) {
+			windowStart := pcommon.NewTimestampFromTime(startTS.AsTime().Add(time.Duration(window) * time.Minute))
+			var mainMetrics pmetric.Metrics = pmetric.NewMetrics()
+			for _, device := range []struct {
+				id    string
+				value float64
+			}{
+				{"a", 0},
+				{"b", 100},
+			} {
+				deviceMetrics := CreateDoubleGaugeArgument("volume", startTS, []float64{device.value})
+				SetGaugeTimestamps(deviceMetrics, []pcommon.Timestamp{pcommon.NewTimestampFromTime(windowStart.AsTime().Add(30 * time.Second))})
+				resourceMetric := deviceMetrics.ResourceMetrics().At(0)
+				_ = resourceMetric.Resource().Attributes().FromRaw(map[string]any{"device.id": device.id, "device.model": "x"})
+				resourceMetric.MoveTo(mainMetrics.ResourceMetrics().AppendEmpty())
+			}
+
+			var resourcesMaps map[string]*ResourceContainer = make(map[string]*ResourceContainer)
+			processor.AggregateMetrics(resourcesMaps, mainMetrics)
+			finalMetrics := processor.CreateMetrics(resourcesMaps, pcommon.NewTimestampFromTime(windowStart.AsTime().Add(time.Minute)))
+
+			var twa bool = false
+			for i := 0; i < finalMetrics.ResourceMetrics().Len(); i++ {
+				resourceMetric := finalMetrics.ResourceMetrics().At(i)
+				if CreateAttributesKey(resourceMetric.Resource().Attributes()) != "device.model=x" {
+					continue
+				}
+				metric := resourceMetric.ScopeMetrics().At(0).Metrics().At(0)
+				assert.Equal(t, "volume_gauge_twa", metric.Name())
+				ValidateDoubleGauge(t, metric, &twa, 50, startTS)
+			}
+			assert.True(t, twa)
+		})
+	}
+}
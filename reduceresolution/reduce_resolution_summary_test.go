@@ -0,0 +1,112 @@
+// Copyright (C) 2025 Bang & Olufsen A/S, Denmark
+//
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package reduceresolution
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.uber.org/zap"
+)
+
+func TestValidateSummaryAggregation(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+	values := []SummaryValue{
+		{pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC)), 2, 10.0, []SummaryQuantile{{0.5, 4.0}, {0.99, 6.0}}},
+		{pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 20, 0, time.UTC)), 4, 16.0, []SummaryQuantile{{0.5, 3.0}, {0.99, 8.0}}},
+	}
+
+	for _, test := range []struct {
+		name      string
+		mode      string
+		quantiles []SummaryQuantile
+	}{
+		{"validate the quantiles of the latest datapoint are kept", SummaryQuantilesLatest, []SummaryQuantile{{0.5, 3.0}, {0.99, 8.0}}},
+		{"validate the highest value of each quantile is kept", SummaryQuantilesMax, []SummaryQuantile{{0.5, 4.0}, {0.99, 8.0}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var processor *ReduceResolution = &ReduceResolution{
+				Logger: logger,
+				Config: ProcessedConfig{SummaryQuantiles: test.mode},
+			}
+			finalMetrics, error := processor.ProcessMetrics(nil, CreateSummaryArgument("request_duration", startTS, values))
+
+			assert.NoError(t, error)
+			var summary bool = false
+			scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+			assert.Equal(t, 1, scope.Metrics().Len())
+			ValidateSummary(t, scope.Metrics().At(0), &summary, 4, 16.0, test.quantiles, startTS)
+			assert.True(t, summary)
+		})
+	}
+}
+
+func TestValidateSummaryAggregationDropAttributes(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsOptions: map[string]MetricOptions{
+				"request_duration": {DropAttributes: []string{"path"}, SummaryQuantiles: SummaryQuantilesMax},
+			},
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+	ts := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	mainMetrics := CreateSummaryArgument("request_duration", startTS, []SummaryValue{
+		{ts, 2, 10.0, []SummaryQuantile{{0.5, 4.0}, {0.99, 6.0}}},
+		{ts, 3, 6.0, []SummaryQuantile{{0.5, 5.0}, {0.9, 5.5}}},
+	})
+	SetDatapointAttributes(mainMetrics, []map[string]any{{"path": "/a"}, {"path": "/b"}})
+
+	t.Run("validate the count and sum of the series are added up", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, mainMetrics)
+
+		assert.NoError(t, error)
+		var summary bool = false
+		scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+		assert.Equal(t, 1, scope.Metrics().Len())
+		ValidateSummary(t, scope.Metrics().At(0), &summary, 5, 16.0, []SummaryQuantile{{0.5, 5.0}, {0.9, 5.5}, {0.99, 6.0}}, startTS)
+		assert.True(t, summary)
+	})
+}
+
+func TestValidateSummaryResets(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC))
+	resetTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	values := []SummaryValue{
+		{resetTS, 4, 16.0, []SummaryQuantile{{0.5, 3.0}}},
+		{pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 20, 0, time.UTC)), 1, 2.0, []SummaryQuantile{{0.5, 2.0}}},
+	}
+
+	for _, test := range []struct {
+		name    string
+		resets  string
+		count   uint64
+		sum     float64
+		startTS pcommon.Timestamp
+	}{
+		{"validate the count and sum before the reset are accumulated", CumulativeResetsAccumulate, 5, 18.0, startTS},
+		{"validate the series restarts at the reset", CumulativeResetsRestart, 1, 2.0, resetTS},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var processor *ReduceResolution = &ReduceResolution{
+				Logger: logger,
+				Config: ProcessedConfig{CumulativeResets: test.resets},
+			}
+			finalMetrics, error := processor.ProcessMetrics(nil, CreateSummaryArgument("request_duration", startTS, values))
+
+			assert.NoError(t, error)
+			var summary bool = false
+			scope := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0)
+			ValidateSummary(t, scope.Metrics().At(0), &summary, test.count, test.sum, []SummaryQuantile{{0.5, 2.0}}, test.startTS)
+			assert.True(t, summary)
+		})
+	}
+}
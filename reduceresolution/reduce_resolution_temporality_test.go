@@ -0,0 +1,359 @@
+// Copyright (C) 2025 Bang & Olufsen A/S, Denmark
+//
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package reduceresolution
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+func TestValidateCounterCumulativeToDelta(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{},
+			OutputTemporality: OutputTemporalityDelta,
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	var previousTS pcommon.Timestamp
+
+	for _, window := range []struct {
+		name      string
+		ts        pcommon.Timestamp
+		value     int64
+		delta     int64
+		continued bool
+	}{
+		{"validate the first window emits the whole value", pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC)), 10, 10, false},
+		{"validate the next window emits the increase", pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 1, 30, 0, time.UTC)), 25, 15, true},
+	} {
+		t.Run(window.name, func(t *testing.T) {
+			finalMetrics, error := processor.ProcessMetrics(nil, CreateIntCounterArgument("testcounter", startTS, window.ts, true, []int64{window.value}))
+
+			assert.NoError(t, error)
+			var counter bool = false
+			metric := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+			expectedStartTS := startTS
+			if window.continued {
+				expectedStartTS = previousTS
+			}
+			ValidateIntCounter(t, metric, &counter, false, true, window.delta, expectedStartTS)
+			assert.True(t, counter)
+			previousTS = metric.Sum().DataPoints().At(0).Timestamp()
+		})
+	}
+}
+
+func TestValidateSeriesExpiry(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{},
+			OutputTemporality: OutputTemporalityDelta,
+			SeriesExpiry:      2,
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+
+	for _, window := range []struct {
+		name   string
+		metric string
+		ts     pcommon.Timestamp
+		value  int64
+		delta  int64
+		series []string
+	}{
+		{"validate the state of a new series is kept", "first_counter", pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC)), 10, 10, []string{"first_counter"}},
+		{"validate the state is kept within the expiry", "second_counter", pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 1, 30, 0, time.UTC)), 5, 5, []string{"first_counter", "second_counter"}},
+		{"validate the state is dropped after the expiry", "second_counter", pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 2, 30, 0, time.UTC)), 6, 1, []string{"second_counter"}},
+		{"validate an expired series starts over", "first_counter", pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 3, 30, 0, time.UTC)), 30, 30, []string{"first_counter", "second_counter"}},
+	} {
+		t.Run(window.name, func(t *testing.T) {
+			finalMetrics, error := processor.ProcessMetrics(nil, CreateIntCounterArgument(window.metric, startTS, window.ts, true, []int64{window.value}))
+
+			assert.NoError(t, error)
+			var counter bool = false
+			metric := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+			assert.Equal(t, window.metric, metric.Name())
+			if window.delta == window.value {
+				ValidateIntCounter(t, metric, &counter, false, true, window.delta, startTS)
+				assert.True(t, counter)
+			} else {
+				assert.Equal(t, window.delta, metric.Sum().DataPoints().At(0).IntValue())
+			}
+
+			assert.Equal(t, len(window.series), len(processor.series))
+			for _, name := range window.series {
+				wasChecked := false
+				for key := range processor.series {
+					if strings.Contains(key, "|"+name+"@") {
+						wasChecked = true
+					}
+				}
+				assert.True(t, wasChecked, name)
+			}
+		})
+	}
+}
+
+func TestValidateCounterDeltaToCumulative(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			MetricsStatistics: map[string][]Statistic{},
+			OutputTemporality: OutputTemporalityCumulative,
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	ts := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC))
+
+	t.Run("validate the running total is emitted from the first start", func(t *testing.T) {
+		windowStartTS := startTS
+		for _, window := range []struct {
+			values []int64
+			total  int64
+		}{
+			{[]int64{1, 2}, 3},
+			{[]int64{4}, 7},
+		} {
+			finalMetrics, error := processor.ProcessMetrics(nil, CreateIntCounterArgument("testcounter", windowStartTS, ts, false, window.values))
+
+			assert.NoError(t, error)
+			var counter bool = false
+			metric := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+			ValidateIntCounter(t, metric, &counter, true, true, window.total, startTS)
+			assert.True(t, counter)
+			windowStartTS = ts
+			ts = pcommon.NewTimestampFromTime(ts.AsTime().Add(time.Minute))
+		}
+	})
+}
+
+func TestValidateHistogramCumulativeToDelta(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{OutputTemporality: OutputTemporalityDelta},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	histogramArgument := func(ts pcommon.Timestamp, value HistogramValue) pmetric.Metrics {
+		return CreateArgument(
+			MetricArg{
+				[]ResourceMetricsArg{
+					{
+						[]ScopeArg{
+							{
+								"testscope",
+								"1.0",
+								[]GaugeArg[float64]{},
+								[]GaugeArg[int64]{},
+								[]CounterArg[float64]{},
+								[]CounterArg[int64]{},
+								[]HistogramArg{
+									{"testhistogram", startTS, ts, true, []float64{0.0, 5.0, 10.0}, []HistogramValue{value}},
+								},
+							},
+						},
+					},
+				},
+			},
+		)
+	}
+
+	t.Run("validate the next window emits the new observations", func(t *testing.T) {
+		firstMetrics, error := processor.ProcessMetrics(nil, histogramArgument(
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC)),
+			HistogramValue{2, 6.0, 4.0, 2.0, []uint64{0, 2, 0, 0}},
+		))
+		assert.NoError(t, error)
+		firstTS := firstMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0).Timestamp()
+
+		finalMetrics, error := processor.ProcessMetrics(nil, histogramArgument(
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 1, 30, 0, time.UTC)),
+			HistogramValue{5, 30.0, 12.0, 2.0, []uint64{0, 3, 1, 1}},
+		))
+		assert.NoError(t, error)
+
+		metric := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+		assert.Equal(t, pmetric.AggregationTemporalityDelta, metric.Histogram().AggregationTemporality())
+		dp := metric.Histogram().DataPoints().At(0)
+		assert.Equal(t, firstTS, dp.StartTimestamp())
+		assert.Equal(t, uint64(3), dp.Count())
+		assert.Equal(t, 24.0, dp.Sum())
+		assert.Equal(t, []uint64{0, 1, 1, 1}, dp.BucketCounts().AsRaw())
+		assert.False(t, dp.HasMin())
+		assert.False(t, dp.HasMax())
+	})
+	t.Run("validate a bucket that decreased is handled like a reset", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, histogramArgument(
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 2, 30, 0, time.UTC)),
+			HistogramValue{6, 40.0, 12.0, 2.0, []uint64{0, 2, 2, 2}},
+		))
+		assert.NoError(t, error)
+
+		dp := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0)
+		assert.Equal(t, startTS, dp.StartTimestamp())
+		assert.Equal(t, uint64(6), dp.Count())
+		assert.Equal(t, []uint64{0, 2, 2, 2}, dp.BucketCounts().AsRaw())
+	})
+}
+
+func TestValidateHistogramDeltaToCumulative(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{
+			OutputTemporality: OutputTemporalityCumulative,
+			SeriesExpiry:      DefaultSeriesExpiry,
+		},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+
+	t.Run("validate the running total keeps growing past the series expiry", func(t *testing.T) {
+		windowStartTS := startTS
+		for window := 1; window <= 3*DefaultSeriesExpiry; window++ {
+			ts := pcommon.NewTimestampFromTime(windowStartTS.AsTime().Add(time.Minute))
+			metrics := CreateArgument(
+				MetricArg{
+					[]ResourceMetricsArg{
+						{
+							[]ScopeArg{
+								{
+									"testscope",
+									"1.0",
+									[]GaugeArg[float64]{},
+									[]GaugeArg[int64]{},
+									[]CounterArg[float64]{},
+									[]CounterArg[int64]{},
+									[]HistogramArg{
+										{"testhistogram", windowStartTS, ts, false, []float64{0.0, 5.0}, []HistogramValue{{1, 2.0, 0, 0, []uint64{0, 1, 0}}}},
+									},
+								},
+							},
+						},
+					},
+				},
+			)
+			// Like the histograms converted from Prometheus, which have no minimum and maximum
+			dp := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0)
+			dp.RemoveMin()
+			dp.RemoveMax()
+
+			finalMetrics, error := processor.ProcessMetrics(nil, metrics)
+
+			assert.NoError(t, error)
+			metric := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+			assert.Equal(t, pmetric.AggregationTemporalityCumulative, metric.Histogram().AggregationTemporality())
+			dp = metric.Histogram().DataPoints().At(0)
+			assert.Equal(t, startTS, dp.StartTimestamp())
+			assert.Equal(t, uint64(window), dp.Count(), window)
+			assert.Equal(t, []uint64{0, uint64(window), 0}, dp.BucketCounts().AsRaw())
+			assert.False(t, dp.HasMin())
+			assert.False(t, dp.HasMax())
+			windowStartTS = ts
+		}
+	})
+}
+
+func TestValidateExponentialHistogramCumulativeToDelta(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{OutputTemporality: OutputTemporalityDelta},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+	histogramArgument := func(ts pcommon.Timestamp, value ExponentialHistogramValue) pmetric.Metrics {
+		return CreateExponentialHistogramArgument([]ExponentialHistogramArg{
+			{"testhistogram", startTS, ts, true, []ExponentialHistogramValue{value}},
+		})
+	}
+
+	t.Run("validate the next window emits the new observations at the lowest scale", func(t *testing.T) {
+		firstMetrics, error := processor.ProcessMetrics(nil, histogramArgument(
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 30, 0, time.UTC)),
+			ExponentialHistogramValue{3, 3.0, 1.5, 0.0, 2, 1, ExponentialBuckets{0, []uint64{1, 1}}, ExponentialBuckets{}},
+		))
+		assert.NoError(t, error)
+		firstTS := firstMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).ExponentialHistogram().DataPoints().At(0).Timestamp()
+
+		finalMetrics, error := processor.ProcessMetrics(nil, histogramArgument(
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 1, 30, 0, time.UTC)),
+			ExponentialHistogramValue{5, 8.0, 3.0, 0.0, 1, 1, ExponentialBuckets{0, []uint64{3, 1}}, ExponentialBuckets{}},
+		))
+		assert.NoError(t, error)
+
+		metric := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+		assert.Equal(t, pmetric.AggregationTemporalityDelta, metric.ExponentialHistogram().AggregationTemporality())
+		dp := metric.ExponentialHistogram().DataPoints().At(0)
+		assert.Equal(t, firstTS, dp.StartTimestamp())
+		assert.Equal(t, uint64(2), dp.Count())
+		assert.Equal(t, 5.0, dp.Sum())
+		assert.Equal(t, int32(1), dp.Scale())
+		assert.Equal(t, uint64(0), dp.ZeroCount())
+		assert.Equal(t, int32(0), dp.Positive().Offset())
+		assert.Equal(t, []uint64{1, 1}, dp.Positive().BucketCounts().AsRaw())
+		assert.False(t, dp.HasMin())
+		assert.False(t, dp.HasMax())
+	})
+	t.Run("validate a bucket that decreased is handled like a reset", func(t *testing.T) {
+		finalMetrics, error := processor.ProcessMetrics(nil, histogramArgument(
+			pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 2, 30, 0, time.UTC)),
+			ExponentialHistogramValue{6, 12.0, 3.0, 0.0, 1, 1, ExponentialBuckets{0, []uint64{2, 3}}, ExponentialBuckets{}},
+		))
+		assert.NoError(t, error)
+
+		var histogram bool = false
+		metric := finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+		ValidateExponentialHistogram(t, metric, &histogram, false,
+			ExponentialHistogramValue{6, 12.0, 3.0, 0.0, 1, 1, ExponentialBuckets{0, []uint64{2, 3}}, ExponentialBuckets{}}, startTS)
+		assert.True(t, histogram)
+	})
+}
+
+func TestValidateExponentialHistogramDeltaToCumulative(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	var processor *ReduceResolution = &ReduceResolution{
+		Logger: logger,
+		Config: ProcessedConfig{OutputTemporality: OutputTemporalityCumulative},
+	}
+	startTS := pcommon.NewTimestampFromTime(time.Date(2025, time.January, 1, 12, 0, 10, 0, time.UTC))
+
+	t.Run("validate the running total is emitted at the lowest scale", func(t *testing.T) {
+		windowStartTS := startTS
+		for _, test := range []struct {
+			delta    ExponentialHistogramValue
+			expected ExponentialHistogramValue
+		}{
+			{
+				ExponentialHistogramValue{3, 3.0, 1.5, 0.0, 2, 1, ExponentialBuckets{0, []uint64{1, 1}}, ExponentialBuckets{}},
+				ExponentialHistogramValue{3, 3.0, 1.5, 0.0, 2, 1, ExponentialBuckets{0, []uint64{1, 1}}, ExponentialBuckets{}},
+			},
+			{
+				ExponentialHistogramValue{2, 5.0, 3.0, 1.5, 1, 0, ExponentialBuckets{0, []uint64{1, 1}}, ExponentialBuckets{}},
+				ExponentialHistogramValue{5, 8.0, 3.0, 0.0, 1, 1, ExponentialBuckets{0, []uint64{3, 1}}, ExponentialBuckets{}},
+			},
+		} {
+			finalMetrics, error := processor.ProcessMetrics(nil, CreateExponentialHistogramArgument([]ExponentialHistogramArg{
+				{"testhistogram", windowStartTS, pcommon.NewTimestampFromTime(windowStartTS.AsTime().Add(time.Minute)), false, []ExponentialHistogramValue{test.delta}},
+			}))
+			assert.NoError(t, error)
+
+			var histogram bool = false
+			ValidateExponentialHistogram(t, finalMetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0), &histogram, true, test.expected, startTS)
+			assert.True(t, histogram)
+			windowStartTS = pcommon.NewTimestampFromTime(windowStartTS.AsTime().Add(time.Minute))
+		}
+	})
+}
@@ -0,0 +1,68 @@
+// Copyright (C) 2025 Bang & Olufsen A/S, Denmark
+//
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package reduceresolution
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+type ResourceContainer struct {
+	resource  pcommon.Resource
+	schemaUrl string
+
+	scopesMaps map[string]*ScopeContainer
+}
+
+func CreateResourceContainer(resourceMetric pmetric.ResourceMetrics) *ResourceContainer {
+	resource := pcommon.NewResource()
+	resourceMetric.Resource().CopyTo(resource)
+	return &ResourceContainer{
+		resource:   resource,
+		schemaUrl:  resourceMetric.SchemaUrl(),
+		scopesMaps: make(map[string]*ScopeContainer),
+	}
+}
+
+// Creates a unique deterministic key based on a resource's schema URL, and its attributes
+func CreateResourceKey(resourceMetric pmetric.ResourceMetrics) string {
+	return fmt.Sprintf("%s|%s", resourceMetric.SchemaUrl(), CreateAttributesKey(resourceMetric.Resource().Attributes()))
+}
+
+// Merges the resource into the cohort that shares the same values for the configured resource
+// attributes. Resources that have none of those attributes do not belong to any cohort
+func MergeIntoCohort(cohortsMaps map[string]*ResourceContainer, resourceContainer *ResourceContainer, p *ReduceResolution) {
+	cohortResource := pcommon.NewResource()
+	for _, attribute := range p.Config.RollupResourceAttributes {
+		if value, ok := resourceContainer.resource.Attributes().Get(attribute); ok {
+			value.CopyTo(cohortResource.Attributes().PutEmpty(attribute))
+		}
+	}
+	if cohortResource.Attributes().Len() == 0 {
+		return
+	}
+
+	cohortKey := fmt.Sprintf("%s|%s", resourceContainer.schemaUrl, CreateAttributesKey(cohortResource.Attributes()))
+	cohortContainer, ok := cohortsMaps[cohortKey]
+	if !ok {
+		cohortContainer = &ResourceContainer{
+			resource:   cohortResource,
+			schemaUrl:  resourceContainer.schemaUrl,
+			scopesMaps: make(map[string]*ScopeContainer),
+		}
+		cohortsMaps[cohortKey] = cohortContainer
+	}
+
+	for scopeKey, scopeContainer := range resourceContainer.scopesMaps {
+		cohortScopeContainer, ok := cohortContainer.scopesMaps[scopeKey]
+		if !ok {
+			cohortScopeContainer = CreateEmptyScopeContainer(scopeContainer)
+			cohortContainer.scopesMaps[scopeKey] = cohortScopeContainer
+		}
+		MergeScopeContainer(cohortScopeContainer, scopeContainer, p)
+	}
+}
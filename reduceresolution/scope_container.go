@@ -26,55 +26,218 @@ type ScopeContainer struct {
 
 	histogramAggregate map[string]*HistogramAggregate
 
+	exponentialHistogramAggregate map[string]*ExponentialHistogramAggregate
+	summaryAggregate              map[string]*SummaryAggregate
+
 	leftoverMetric []pmetric.Metric
 }
 
 func CreateScopeContainer(scopeMetric pmetric.ScopeMetrics) *ScopeContainer {
+	// The attributes are copied along with the empty aggregates
+	scopeContainer := CreateEmptyScopeContainer(&ScopeContainer{scopeAttributes: scopeMetric.Scope().Attributes()})
+	scopeContainer.scopeName = scopeMetric.Scope().Name()
+	scopeContainer.scopeVersion = scopeMetric.Scope().Version()
+	return scopeContainer
+}
+
+// Creates an empty container for the same scope as another container
+func CreateEmptyScopeContainer(scopeContainer *ScopeContainer) *ScopeContainer {
 	return &ScopeContainer{
-		scopeName:             scopeMetric.Scope().Name(),
-		scopeVersion:          scopeMetric.Scope().Version(),
-		scopeAttributes:       scopeMetric.Scope().Attributes(),
+		scopeName:             scopeContainer.scopeName,
+		scopeVersion:          scopeContainer.scopeVersion,
+		scopeAttributes:       CopyAttributes(scopeContainer.scopeAttributes),
 		intGaugeAggregate:     make(map[string]*GaugeAggregate[int64]),
 		floatGaugeAggregate:   make(map[string]*GaugeAggregate[float64]),
 		intCounterAggregate:   make(map[string]*CounterAggregate[int64]),
 		floatCounterAggregate: make(map[string]*CounterAggregate[float64]),
 		histogramAggregate:    make(map[string]*HistogramAggregate),
-		leftoverMetric:        make([]pmetric.Metric, 0),
+
+		exponentialHistogramAggregate: make(map[string]*ExponentialHistogramAggregate),
+		summaryAggregate:              make(map[string]*SummaryAggregate),
+
+		leftoverMetric: make([]pmetric.Metric, 0),
 	}
 }
 
-// Creates a unique deterministic key based on a scope's name, version, and its attributes
-func CreateScopeKey(scopeMetric pmetric.ScopeMetrics) string {
-	scope_keys := make([]string, 0, scopeMetric.Scope().Attributes().Len())
-	for k := range scopeMetric.Scope().Attributes().AsRaw() {
-		scope_keys = append(scope_keys, k)
+// Copies the metric into the container, so it does not depend on the lifetime of the received batch
+func AddLeftoverMetric(scopeContainer *ScopeContainer, metric pmetric.Metric) {
+	leftover := pmetric.NewMetric()
+	metric.CopyTo(leftover)
+	scopeContainer.leftoverMetric = append(scopeContainer.leftoverMetric, leftover)
+}
+
+// Creates a unique deterministic key based on a set of attributes
+func CreateAttributesKey(attributes pcommon.Map) string {
+	keys := make([]string, 0, attributes.Len())
+	for k := range attributes.AsRaw() {
+		keys = append(keys, k)
 	}
 
-	sort.Strings(scope_keys)
+	sort.Strings(keys)
 	var attributeParts []string
-	for _, k := range scope_keys {
-		value, _ := scopeMetric.Scope().Attributes().Get(k)
+	for _, k := range keys {
+		value, _ := attributes.Get(k)
 		attributeParts = append(attributeParts, fmt.Sprintf("%s=%s", k, value.AsString()))
 	}
-	attributesStrings := strings.Join(attributeParts, ",")
-	return fmt.Sprintf("%s|%s|%s", scopeMetric.Scope().Name(), scopeMetric.Scope().Version(), attributesStrings)
+	return strings.Join(attributeParts, ",")
+}
 
+// Creates a unique deterministic key based on a scope's name, version, and its attributes
+func CreateScopeKey(scopeMetric pmetric.ScopeMetrics) string {
+	return fmt.Sprintf("%s|%s|%s", scopeMetric.Scope().Name(), scopeMetric.Scope().Version(), CreateAttributesKey(scopeMetric.Scope().Attributes()))
 }
 
 // Creates a unique deterministic key based on a metric's name, and its attributes
 func CreateMetricKey(metric pmetric.Metric, attributes pcommon.Map) string {
-	metric_keys := make([]string, 0, attributes.Len())
-	for k := range attributes.AsRaw() {
-		metric_keys = append(metric_keys, k)
+	return fmt.Sprintf("%s@%s", metric.Name(), CreateAttributesKey(attributes))
+}
+
+// Returns a copy of the attributes that is not tied to the received batch
+func CopyAttributes(attributes pcommon.Map) pcommon.Map {
+	attributesCopy := pcommon.NewMap()
+	attributes.CopyTo(attributesCopy)
+	return attributesCopy
+}
+
+// Returns the attributes that remain after applying the keep and drop lists of a metric
+func FilterAttributes(attributes pcommon.Map, options MetricOptions) pcommon.Map {
+	filtered := CopyAttributes(attributes)
+	if len(options.KeepAttributes) > 0 {
+		filtered.RemoveIf(func(k string, _ pcommon.Value) bool {
+			for _, keep := range options.KeepAttributes {
+				if k == keep {
+					return false
+				}
+			}
+			return true
+		})
+	}
+	for _, drop := range options.DropAttributes {
+		filtered.Remove(drop)
 	}
+	return filtered
+}
 
-	sort.Strings(metric_keys)
+// Merges the series of the metrics that are configured to keep or drop attributes. The scope key identifies
+// the series across windows
+func ReduceAttributes(scopeContainer *ScopeContainer, scopeKey string, p *ReduceResolution) {
+	scopeContainer.intGaugeAggregate = ReduceSeries(scopeContainer.intGaugeAggregate, p,
+		func(aggregate *GaugeAggregate[int64]) (string, pcommon.Map) {
+			return aggregate.name, aggregate.attributes
+		},
+		CopyGaugeAggregate[int64],
+		MergeGaugeAggregate[int64])
+	scopeContainer.floatGaugeAggregate = ReduceSeries(scopeContainer.floatGaugeAggregate, p,
+		func(aggregate *GaugeAggregate[float64]) (string, pcommon.Map) {
+			return aggregate.name, aggregate.attributes
+		},
+		CopyGaugeAggregate[float64],
+		MergeGaugeAggregate[float64])
+	scopeContainer.intCounterAggregate = ReduceCounterSeries(scopeContainer.intCounterAggregate, scopeKey, p)
+	scopeContainer.floatCounterAggregate = ReduceCounterSeries(scopeContainer.floatCounterAggregate, scopeKey, p)
+	scopeContainer.histogramAggregate = ReduceSeries(scopeContainer.histogramAggregate, p,
+		func(aggregate *HistogramAggregate) (string, pcommon.Map) {
+			return aggregate.name, aggregate.attributes
+		},
+		CopyHistogramAggregate,
+		func(aggregate *HistogramAggregate, other *HistogramAggregate) {
+			if MergeHistogramAggregate(aggregate, other) != 0 {
+				p.Logger.Warn("Histogram series dropped due to mismatch while reducing attributes of " + aggregate.name)
+			}
+		})
+	scopeContainer.exponentialHistogramAggregate = ReduceSeries(scopeContainer.exponentialHistogramAggregate, p,
+		func(aggregate *ExponentialHistogramAggregate) (string, pcommon.Map) {
+			return aggregate.name, aggregate.attributes
+		},
+		CopyExponentialHistogramAggregate,
+		MergeExponentialHistogramAggregate)
+	scopeContainer.summaryAggregate = ReduceSeries(scopeContainer.summaryAggregate, p,
+		func(aggregate *SummaryAggregate) (string, pcommon.Map) {
+			return aggregate.name, aggregate.attributes
+		},
+		CopySummaryAggregate,
+		MergeSummaryAggregate)
+}
 
-	var attributeParts []string
-	for _, k := range metric_keys {
-		value, _ := attributes.Get(k)
-		attributeParts = append(attributeParts, fmt.Sprintf("%s=%s", k, value.AsString()))
+// Groups the aggregates by their reduced attributes, merging the ones that end up in the same series
+func ReduceSeries[A any](
+	aggregates map[string]*A,
+	p *ReduceResolution,
+	series func(*A) (string, pcommon.Map),
+	copyAggregate func(*A, pcommon.Map) *A,
+	mergeAggregate func(*A, *A),
+) map[string]*A {
+	reduced := make(map[string]*A, len(aggregates))
+	for key, aggregate := range aggregates {
+		name, attributes := series(aggregate)
+		options := p.MetricOptions(name)
+		if !options.ReducesAttributes() {
+			reduced[key] = aggregate
+			continue
+		}
+
+		filtered := FilterAttributes(attributes, options)
+		reducedKey := fmt.Sprintf("%s@%s", name, CreateAttributesKey(filtered))
+		if existing, ok := reduced[reducedKey]; ok {
+			mergeAggregate(existing, aggregate)
+		} else {
+			reduced[reducedKey] = copyAggregate(aggregate, filtered)
+		}
+	}
+	return reduced
+}
+
+// Merges the aggregates of another container into this one, leaving the other container untouched.
+// The leftover metrics are not merged, since they were never aggregated
+func MergeScopeContainer(scopeContainer *ScopeContainer, other *ScopeContainer, p *ReduceResolution) {
+	MergeSeries(scopeContainer.intGaugeAggregate, other.intGaugeAggregate,
+		func(aggregate *GaugeAggregate[int64]) *GaugeAggregate[int64] {
+			return CopyGaugeAggregate(aggregate, CopyAttributes(aggregate.attributes))
+		},
+		MergeGaugeAggregate[int64])
+	MergeSeries(scopeContainer.floatGaugeAggregate, other.floatGaugeAggregate,
+		func(aggregate *GaugeAggregate[float64]) *GaugeAggregate[float64] {
+			return CopyGaugeAggregate(aggregate, CopyAttributes(aggregate.attributes))
+		},
+		MergeGaugeAggregate[float64])
+	MergeSeries(scopeContainer.intCounterAggregate, other.intCounterAggregate,
+		func(aggregate *CounterAggregate[int64]) *CounterAggregate[int64] {
+			return CopyCounterAggregate(aggregate, CopyAttributes(aggregate.attributes))
+		},
+		MergeCounterAggregate[int64])
+	MergeSeries(scopeContainer.floatCounterAggregate, other.floatCounterAggregate,
+		func(aggregate *CounterAggregate[float64]) *CounterAggregate[float64] {
+			return CopyCounterAggregate(aggregate, CopyAttributes(aggregate.attributes))
+		},
+		MergeCounterAggregate[float64])
+	MergeSeries(scopeContainer.histogramAggregate, other.histogramAggregate,
+		func(aggregate *HistogramAggregate) *HistogramAggregate {
+			return CopyHistogramAggregate(aggregate, CopyAttributes(aggregate.attributes))
+		},
+		func(aggregate *HistogramAggregate, other *HistogramAggregate) {
+			if MergeHistogramAggregate(aggregate, other) != 0 {
+				p.Logger.Warn("Histogram series dropped due to mismatch while merging " + aggregate.name)
+			}
+		})
+	MergeSeries(scopeContainer.exponentialHistogramAggregate, other.exponentialHistogramAggregate,
+		func(aggregate *ExponentialHistogramAggregate) *ExponentialHistogramAggregate {
+			return CopyExponentialHistogramAggregate(aggregate, CopyAttributes(aggregate.attributes))
+		},
+		MergeExponentialHistogramAggregate)
+	MergeSeries(scopeContainer.summaryAggregate, other.summaryAggregate,
+		func(aggregate *SummaryAggregate) *SummaryAggregate {
+			return CopySummaryAggregate(aggregate, CopyAttributes(aggregate.attributes))
+		},
+		MergeSummaryAggregate)
+}
+
+// Merges every series of the source into the series with the same key in the target
+func MergeSeries[A any](target map[string]*A, source map[string]*A, copyAggregate func(*A) *A, mergeAggregate func(*A, *A)) {
+	for key, aggregate := range source {
+		if existing, ok := target[key]; ok {
+			mergeAggregate(existing, aggregate)
+		} else {
+			target[key] = copyAggregate(aggregate)
+		}
 	}
-	attributesStrings := strings.Join(attributeParts, ",")
-	return fmt.Sprintf("%s@%s", metric.Name(), attributesStrings)
 }
@@ -0,0 +1,214 @@
+// Copyright (C) 2025 Bang & Olufsen A/S, Denmark
+//
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package reduceresolution
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// Statistic is one of the values a gauge can be reduced to, as configured in gauge-aggregations
+type Statistic string
+
+const (
+	StatisticAvg      Statistic = "avg"
+	StatisticSum      Statistic = "sum"
+	StatisticMin      Statistic = "min"
+	StatisticMax      Statistic = "max"
+	StatisticAbsMin   Statistic = "abs_min"
+	StatisticAbsMax   Statistic = "abs_max"
+	StatisticFirst    Statistic = "first"
+	StatisticLast     Statistic = "last"
+	StatisticTWA      Statistic = "twa"
+	StatisticRange    Statistic = "range"
+	StatisticVariance Statistic = "variance"
+	StatisticStddev   Statistic = "stddev"
+	StatisticCount    Statistic = "count"
+
+	// Forwards the datapoints of a gauge unchanged, instead of aggregating them
+	StatisticPassthrough Statistic = "passthrough"
+
+	// Only for counters
+	StatisticIncrease Statistic = "increase"
+	StatisticRate     Statistic = "rate"
+	StatisticMaxRate  Statistic = "max_rate"
+
+	// Only for histograms
+	StatisticMean Statistic = "mean"
+)
+
+// StatisticSet holds the statistics that can be configured for one type of metric
+type StatisticSet struct {
+	metricType  string
+	known       map[Statistic]bool
+	percentiles bool
+}
+
+var GaugeStatisticSet = StatisticSet{
+	metricType: "gauges",
+	known: map[Statistic]bool{
+		StatisticAvg:         true,
+		StatisticSum:         true,
+		StatisticMin:         true,
+		StatisticMax:         true,
+		StatisticAbsMin:      true,
+		StatisticAbsMax:      true,
+		StatisticFirst:       true,
+		StatisticLast:        true,
+		StatisticTWA:         true,
+		StatisticRange:       true,
+		StatisticVariance:    true,
+		StatisticStddev:      true,
+		StatisticCount:       true,
+		StatisticPassthrough: true,
+	},
+	percentiles: true,
+}
+
+var SumStatisticSet = StatisticSet{
+	metricType: "sums",
+	known: map[Statistic]bool{
+		StatisticSum:      true,
+		StatisticIncrease: true,
+		StatisticRate:     true,
+		StatisticMaxRate:  true,
+		StatisticLast:     true,
+	},
+}
+
+var HistogramStatisticSet = StatisticSet{
+	metricType: "histograms",
+	known: map[Statistic]bool{
+		StatisticCount: true,
+		StatisticSum:   true,
+		StatisticMean:  true,
+		StatisticMin:   true,
+		StatisticMax:   true,
+	},
+	percentiles: true,
+}
+
+// Parse checks that the name is a statistic of the set, or a percentile like p95 or quantile(0.95) when allowed
+func (set StatisticSet) Parse(name string) (Statistic, error) {
+	statistic := Statistic(name)
+	if set.known[statistic] {
+		return statistic, nil
+	}
+	if set.percentiles && statistic.isPercentile() {
+		return statistic, nil
+	}
+	for _, other := range []StatisticSet{GaugeStatisticSet, SumStatisticSet, HistogramStatisticSet} {
+		if other.known[statistic] || (other.percentiles && statistic.isPercentile()) {
+			return "", fmt.Errorf("statistic %q cannot be used for %s", name, set.metricType)
+		}
+	}
+	return "", fmt.Errorf("unknown statistic %q", name)
+}
+
+// ParseStatistics parses a list of statistics, which must not contain the same statistic twice.
+// passthrough cannot be combined with any other statistic
+func (set StatisticSet) ParseStatistics(names []string) ([]Statistic, error) {
+	if names == nil {
+		return nil, nil
+	}
+	statistics := make([]Statistic, 0, len(names))
+	seen := make(map[string]bool)
+	for i, name := range names {
+		statistic, err := set.Parse(name)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", i, err)
+		}
+		if seen[statistic.Name()] {
+			return nil, fmt.Errorf("%d: duplicate statistic %q", i, name)
+		}
+		if statistic == StatisticPassthrough && len(names) > 1 {
+			return nil, fmt.Errorf("%d: %s cannot be combined with other statistics", i, StatisticPassthrough)
+		}
+		seen[statistic.Name()] = true
+		statistics = append(statistics, statistic)
+	}
+	return statistics, nil
+}
+
+func (statistic Statistic) isPercentile() bool {
+	_, ok := statistic.Quantile()
+	return ok
+}
+
+// Quantile returns the quantile of a percentile statistic
+func (statistic Statistic) Quantile() (float64, bool) {
+	return ParseQuantile(string(statistic))
+}
+
+// Name returns the token used for the statistic in metric names, which is the same for all the
+// spellings of a percentile, like p99_9 for both p99.9 and quantile(0.999)
+func (statistic Statistic) Name() string {
+	if q, ok := statistic.Quantile(); ok {
+		return QuantileName(q)
+	}
+	return string(statistic)
+}
+
+// StatisticsMetrics holds the gauges that carry the statistic as an attribute, by name and unit, so that
+// all the series of a scope append their datapoints to the same gauge
+type StatisticsMetrics map[string]pmetric.Metric
+
+// StatisticsEmitter emits the statistics of a single series as gauges, either one gauge for each
+// statistic named after the configured template, or a shared gauge with the statistic as an attribute
+type StatisticsEmitter struct {
+	p                 *ReduceResolution
+	scope             pmetric.ScopeMetrics
+	statisticsMetrics StatisticsMetrics
+	name              string
+	description       string
+	unit              string
+	attributes        pcommon.Map
+	startTS           pcommon.Timestamp
+	// Used when no name-template is configured
+	defaultTemplate string
+	// Name of the gauge that holds all the statistics when they are carried as an attribute
+	attributeMetricName string
+}
+
+// DataPoint appends the datapoint of a statistic, whose value is left to be set
+func (emitter *StatisticsEmitter) DataPoint(statistic string, unit string, timestamp pcommon.Timestamp) pmetric.NumberDataPoint {
+	p := emitter.p
+	var gauge pmetric.Gauge
+	if p.Config.StatisticNaming == StatisticNamingAttribute {
+		// A statistic in another unit, like a rate or a count, cannot share the gauge of the metric,
+		// so it gets a gauge of its own that is named like without the attribute
+		name := emitter.attributeMetricName
+		if unit != emitter.unit {
+			name = p.StatisticMetricName(emitter.name, emitter.unit, statistic, emitter.defaultTemplate)
+		}
+		key := name + "|" + unit
+		metric, ok := emitter.statisticsMetrics[key]
+		if !ok {
+			metric = emitter.scope.Metrics().AppendEmpty()
+			metric.SetName(name)
+			metric.SetUnit(unit)
+			metric.SetDescription(emitter.description)
+			metric.SetEmptyGauge()
+			emitter.statisticsMetrics[key] = metric
+		}
+		gauge = metric.Gauge()
+	} else {
+		metric := emitter.scope.Metrics().AppendEmpty()
+		metric.SetName(p.StatisticMetricName(emitter.name, emitter.unit, statistic, emitter.defaultTemplate))
+		metric.SetUnit(unit)
+		metric.SetDescription(emitter.description)
+		gauge = metric.SetEmptyGauge()
+	}
+	gauge_dp := gauge.DataPoints().AppendEmpty()
+	gauge_dp.SetStartTimestamp(emitter.startTS)
+	gauge_dp.SetTimestamp(timestamp)
+	emitter.attributes.CopyTo(gauge_dp.Attributes())
+	if p.Config.StatisticNaming == StatisticNamingAttribute {
+		gauge_dp.Attributes().PutStr(p.Config.StatisticAttribute, statistic)
+	}
+	return gauge_dp
+}
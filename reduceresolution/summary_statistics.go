@@ -0,0 +1,160 @@
+// Copyright (C) 2025 Bang & Olufsen A/S, Denmark
+//
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package reduceresolution
+
+import (
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+type SummaryQuantile struct {
+	quantile float64
+	value    float64
+}
+
+type SummaryAggregate struct {
+	count uint64
+	sum   float64
+	// Sorted by quantile
+	quantiles   []SummaryQuantile
+	name        string
+	unit        string
+	description string
+	attributes  pcommon.Map
+	startTS     pcommon.Timestamp
+	lastTS      pcommon.Timestamp
+
+	// Either the quantiles of the latest datapoint are kept, or the highest value of each quantile
+	quantileMode string
+
+	// The count and sum of a summary are cumulative, so its resets are detected like for cumulative histograms
+	CumulativeSeries[SummaryAggregate]
+}
+
+func summaryQuantiles(value pmetric.SummaryDataPoint) []SummaryQuantile {
+	quantiles := make([]SummaryQuantile, 0, value.QuantileValues().Len())
+	for i := 0; i < value.QuantileValues().Len(); i++ {
+		quantile := value.QuantileValues().At(i)
+		quantiles = append(quantiles, SummaryQuantile{quantile.Quantile(), quantile.Value()})
+	}
+	sort.Slice(quantiles, func(i, j int) bool { return quantiles[i].quantile < quantiles[j].quantile })
+	return quantiles
+}
+
+func CreateSummaryAggregate(metric pmetric.Metric, value pmetric.SummaryDataPoint, resets string, quantileMode string) *SummaryAggregate {
+	return &SummaryAggregate{
+		count:       value.Count(),
+		sum:         value.Sum(),
+		quantiles:   summaryQuantiles(value),
+		name:        metric.Name(),
+		unit:        metric.Unit(),
+		description: metric.Description(),
+		attributes:  CopyAttributes(value.Attributes()),
+		startTS:     value.StartTimestamp(),
+		lastTS:      value.Timestamp(),
+
+		quantileMode: quantileMode,
+
+		CumulativeSeries: CreateCumulativeSeries[SummaryAggregate](resets, value.StartTimestamp()),
+	}
+}
+
+// Keeps the highest value of each quantile, including the quantiles that only one of both has
+func MaxSummaryQuantiles(quantiles []SummaryQuantile, other []SummaryQuantile) []SummaryQuantile {
+	merged := make([]SummaryQuantile, 0, len(quantiles)+len(other))
+	i, j := 0, 0
+	for i < len(quantiles) || j < len(other) {
+		switch {
+		case j == len(other) || (i < len(quantiles) && quantiles[i].quantile < other[j].quantile):
+			merged = append(merged, quantiles[i])
+			i++
+		case i == len(quantiles) || other[j].quantile < quantiles[i].quantile:
+			merged = append(merged, other[j])
+			j++
+		default:
+			quantile := quantiles[i]
+			if other[j].value > quantile.value {
+				quantile.value = other[j].value
+			}
+			merged = append(merged, quantile)
+			i++
+			j++
+		}
+	}
+	return merged
+}
+
+func AggregateSummary(aggregate *SummaryAggregate, value pmetric.SummaryDataPoint) {
+	if aggregate.quantileMode == SummaryQuantilesMax {
+		aggregate.quantiles = MaxSummaryQuantiles(aggregate.quantiles, summaryQuantiles(value))
+	}
+	if aggregate.lastTS >= value.Timestamp() {
+		return
+	}
+
+	reset := aggregate.IsReset(value, aggregate.count)
+	aggregate.Continue(value, reset, &aggregate.startTS, aggregate.lastTS, aggregate.count, func() *SummaryAggregate {
+		offset := CopySummaryAggregate(aggregate, aggregate.attributes)
+		offset.offset = nil
+		return offset
+	})
+	aggregate.count = value.Count()
+	aggregate.sum = value.Sum()
+	if aggregate.offset != nil {
+		aggregate.count += aggregate.offset.count
+		aggregate.sum += aggregate.offset.sum
+	}
+	if aggregate.quantileMode != SummaryQuantilesMax {
+		aggregate.quantiles = summaryQuantiles(value)
+	}
+	aggregate.lastTS = value.Timestamp()
+}
+
+// Returns an independent copy of the aggregate with a different set of attributes
+func CopySummaryAggregate(aggregate *SummaryAggregate, attributes pcommon.Map) *SummaryAggregate {
+	aggregateCopy := *aggregate
+	aggregateCopy.quantiles = append([]SummaryQuantile(nil), aggregate.quantiles...)
+	aggregateCopy.attributes = attributes
+	return &aggregateCopy
+}
+
+// Merges another series into the aggregate. The count and sum are added up, while the quantiles are
+// either the ones of the latest series, or the highest of both
+func MergeSummaryAggregate(aggregate *SummaryAggregate, other *SummaryAggregate) {
+	aggregate.count += other.count
+	aggregate.sum += other.sum
+	if aggregate.quantileMode == SummaryQuantilesMax {
+		aggregate.quantiles = MaxSummaryQuantiles(aggregate.quantiles, other.quantiles)
+	} else if aggregate.lastTS < other.lastTS {
+		aggregate.quantiles = append([]SummaryQuantile(nil), other.quantiles...)
+	}
+	if other.startTS < aggregate.startTS {
+		aggregate.startTS = other.startTS
+	}
+	if aggregate.lastTS < other.lastTS {
+		aggregate.lastTS = other.lastTS
+	}
+}
+
+func CreateSummaryMetrics(scope pmetric.ScopeMetrics, aggregate *SummaryAggregate, aggregationTS pcommon.Timestamp) {
+	metric := scope.Metrics().AppendEmpty()
+	metric.SetName(aggregate.name)
+	metric.SetUnit(aggregate.unit)
+	metric.SetDescription(aggregate.description)
+	summary := metric.SetEmptySummary()
+	summary_dp := summary.DataPoints().AppendEmpty()
+	summary_dp.SetStartTimestamp(aggregate.startTS)
+	summary_dp.SetTimestamp(aggregationTS)
+	aggregate.attributes.CopyTo(summary_dp.Attributes())
+	summary_dp.SetCount(aggregate.count)
+	summary_dp.SetSum(aggregate.sum)
+	for _, quantile := range aggregate.quantiles {
+		quantileValue := summary_dp.QuantileValues().AppendEmpty()
+		quantileValue.SetQuantile(quantile.quantile)
+		quantileValue.SetValue(quantile.value)
+	}
+}
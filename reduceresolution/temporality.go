@@ -0,0 +1,298 @@
+// Copyright (C) 2025 Bang & Olufsen A/S, Denmark
+//
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package reduceresolution
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// SeriesState remembers what was received and emitted for a series in the previous windows,
+// so that its temporality can be converted
+type SeriesState struct {
+	seen bool
+	// Window in which the series was last used, to drop the state once the series stopped
+	lastWindow uint64
+	// Start of the received cumulative series, or of the emitted cumulative series
+	startTS pcommon.Timestamp
+	// End of the previous window, which is the start of the next delta
+	lastTS pcommon.Timestamp
+
+	// The last received cumulative values, or the running totals of the received deltas
+	intValue       int64
+	doubleValue    float64
+	count          uint64
+	sum            float64
+	bucketCounts   []uint64
+	explicitBounds []float64
+	exponential    *ExponentialHistogram
+	MinMax
+}
+
+// Returns the state of a series, which is created the first time. The series mutex must be held
+func (p *ReduceResolution) seriesState(key string) *SeriesState {
+	if p.series == nil {
+		p.series = make(map[string]*SeriesState)
+	}
+	state, ok := p.series[key]
+	if !ok {
+		state = &SeriesState{}
+		p.series[key] = state
+	}
+	state.lastWindow = p.windowCount
+	return state
+}
+
+// Ends the window and drops the state of the series that were not used within the configured number
+// of windows, so the series that stopped do not accumulate. The series mutex must be held
+func (p *ReduceResolution) expireSeries() {
+	p.windowCount++
+	if p.Config.SeriesExpiry <= 0 {
+		return
+	}
+	for key, state := range p.series {
+		if p.windowCount-state.lastWindow > uint64(p.Config.SeriesExpiry) {
+			delete(p.series, key)
+		}
+	}
+}
+
+// ConvertTemporality converts the sums, histograms and exponential histograms emitted in the scope from the given index on
+// to the configured output temporality, using the state kept for each series across windows
+func (p *ReduceResolution) ConvertTemporality(resourceKey string, scope pmetric.ScopeMetrics, first int) {
+	var target pmetric.AggregationTemporality
+	switch p.Config.OutputTemporality {
+	case OutputTemporalityDelta:
+		target = pmetric.AggregationTemporalityDelta
+	case OutputTemporalityCumulative:
+		target = pmetric.AggregationTemporalityCumulative
+	default:
+		return
+	}
+
+	p.seriesMutex.Lock()
+	defer p.seriesMutex.Unlock()
+	seriesState := func(metric pmetric.Metric, attributes pcommon.Map) *SeriesState {
+		return p.seriesState("temporality|" + resourceKey + "|" + CreateScopeKey(scope) + "|" + CreateMetricKey(metric, attributes))
+	}
+
+	for k := first; k < scope.Metrics().Len(); k++ {
+		metric := scope.Metrics().At(k)
+		switch metric.Type() {
+		case pmetric.MetricTypeSum:
+			if metric.Sum().AggregationTemporality() == target {
+				continue
+			}
+			for l := 0; l < metric.Sum().DataPoints().Len(); l++ {
+				dp := metric.Sum().DataPoints().At(l)
+				if target == pmetric.AggregationTemporalityDelta {
+					CumulativeToDeltaSum(seriesState(metric, dp.Attributes()), dp, metric.Sum().IsMonotonic())
+				} else {
+					DeltaToCumulativeSum(seriesState(metric, dp.Attributes()), dp)
+				}
+			}
+			metric.Sum().SetAggregationTemporality(target)
+		case pmetric.MetricTypeHistogram:
+			if metric.Histogram().AggregationTemporality() == target {
+				continue
+			}
+			for l := 0; l < metric.Histogram().DataPoints().Len(); l++ {
+				dp := metric.Histogram().DataPoints().At(l)
+				if target == pmetric.AggregationTemporalityDelta {
+					CumulativeToDeltaHistogram(seriesState(metric, dp.Attributes()), dp)
+				} else {
+					DeltaToCumulativeHistogram(seriesState(metric, dp.Attributes()), dp)
+				}
+			}
+			metric.Histogram().SetAggregationTemporality(target)
+		case pmetric.MetricTypeExponentialHistogram:
+			if metric.ExponentialHistogram().AggregationTemporality() == target {
+				continue
+			}
+			maxBuckets := p.ExponentialMaxBuckets(metric.Name())
+			for l := 0; l < metric.ExponentialHistogram().DataPoints().Len(); l++ {
+				dp := metric.ExponentialHistogram().DataPoints().At(l)
+				if target == pmetric.AggregationTemporalityDelta {
+					CumulativeToDeltaExponentialHistogram(seriesState(metric, dp.Attributes()), dp, maxBuckets)
+				} else {
+					DeltaToCumulativeExponentialHistogram(seriesState(metric, dp.Attributes()), dp, maxBuckets)
+				}
+			}
+			metric.ExponentialHistogram().SetAggregationTemporality(target)
+		}
+	}
+}
+
+// CumulativeToDeltaSum replaces the cumulative value by the increase since the previous window.
+// The first window of a series, or the one after a reset, emits the whole cumulative value
+func CumulativeToDeltaSum(state *SeriesState, dp pmetric.NumberDataPoint, monotonic bool) {
+	reset := !state.seen || dp.StartTimestamp() != state.startTS
+	switch dp.ValueType() {
+	case pmetric.NumberDataPointValueTypeInt:
+		value := dp.IntValue()
+		reset = reset || (monotonic && value < state.intValue)
+		if !reset {
+			dp.SetIntValue(value - state.intValue)
+		}
+		state.intValue = value
+	case pmetric.NumberDataPointValueTypeDouble:
+		value := dp.DoubleValue()
+		reset = reset || (monotonic && value < state.doubleValue)
+		if !reset {
+			dp.SetDoubleValue(value - state.doubleValue)
+		}
+		state.doubleValue = value
+	}
+
+	state.seen = true
+	state.startTS = dp.StartTimestamp()
+	if !reset {
+		dp.SetStartTimestamp(state.lastTS)
+	}
+	state.lastTS = dp.Timestamp()
+}
+
+// DeltaToCumulativeSum replaces the delta value by the running total since the series was first seen
+func DeltaToCumulativeSum(state *SeriesState, dp pmetric.NumberDataPoint) {
+	if !state.seen {
+		state.seen = true
+		state.startTS = dp.StartTimestamp()
+	}
+	switch dp.ValueType() {
+	case pmetric.NumberDataPointValueTypeInt:
+		state.intValue += dp.IntValue()
+		dp.SetIntValue(state.intValue)
+	case pmetric.NumberDataPointValueTypeDouble:
+		state.doubleValue += dp.DoubleValue()
+		dp.SetDoubleValue(state.doubleValue)
+	}
+	dp.SetStartTimestamp(state.startTS)
+	state.lastTS = dp.Timestamp()
+}
+
+// CumulativeToDeltaHistogram replaces the cumulative buckets by the observations since the previous window.
+// The minimum and maximum of those observations are unknown, so they are removed
+func CumulativeToDeltaHistogram(state *SeriesState, dp pmetric.HistogramDataPoint) {
+	count := dp.Count()
+	sum := dp.Sum()
+	bucketCounts := dp.BucketCounts().AsRaw()
+	explicitBounds := dp.ExplicitBounds().AsRaw()
+	// A bucket that decreased cannot be subtracted from, so it is handled like a reset
+	deltaCounts, increased := BucketCountsIncrease(bucketCounts, state.bucketCounts)
+	reset := !state.seen || dp.StartTimestamp() != state.startTS || count < state.count ||
+		!CompareFloat64SlicesEqual(state.explicitBounds, explicitBounds) || !increased
+
+	state.seen = true
+	state.startTS = dp.StartTimestamp()
+	if !reset {
+		dp.BucketCounts().FromRaw(deltaCounts)
+		dp.SetCount(count - state.count)
+		dp.SetSum(sum - state.sum)
+		dp.RemoveMin()
+		dp.RemoveMax()
+		dp.SetStartTimestamp(state.lastTS)
+	}
+	state.count = count
+	state.sum = sum
+	state.bucketCounts = bucketCounts
+	state.explicitBounds = explicitBounds
+	state.lastTS = dp.Timestamp()
+}
+
+// DeltaToCumulativeHistogram replaces the delta buckets by the running totals since the series was first seen.
+// The totals start again when the bounds change
+func DeltaToCumulativeHistogram(state *SeriesState, dp pmetric.HistogramDataPoint) {
+	bucketCounts := dp.BucketCounts().AsRaw()
+	explicitBounds := dp.ExplicitBounds().AsRaw()
+	if !state.seen || !CompareFloat64SlicesEqual(state.explicitBounds, explicitBounds) || len(state.bucketCounts) != len(bucketCounts) {
+		// Only the totals start again, the window in which the series was last used is kept
+		state.seen = true
+		state.startTS = dp.StartTimestamp()
+		state.count = 0
+		state.sum = 0
+		state.MinMax = MinMax{}
+		state.bucketCounts = make([]uint64, len(bucketCounts))
+		state.explicitBounds = explicitBounds
+	}
+
+	for i := range bucketCounts {
+		state.bucketCounts[i] += bucketCounts[i]
+	}
+	state.count += dp.Count()
+	state.sum += dp.Sum()
+	state.MergeMinMax(DataPointMinMax(dp))
+
+	dp.BucketCounts().FromRaw(state.bucketCounts)
+	dp.SetCount(state.count)
+	dp.SetSum(state.sum)
+	if state.hasMin {
+		dp.SetMin(state.min)
+	}
+	if state.hasMax {
+		dp.SetMax(state.max)
+	}
+	dp.SetStartTimestamp(state.startTS)
+	state.lastTS = dp.Timestamp()
+}
+
+// CumulativeToDeltaExponentialHistogram replaces the cumulative buckets by the observations since the previous window,
+// at the lowest of the scales of both windows. The minimum and maximum of those observations are unknown, so they are removed
+func CumulativeToDeltaExponentialHistogram(state *SeriesState, dp pmetric.ExponentialHistogramDataPoint, maxBuckets int) {
+	count := dp.Count()
+	sum := dp.Sum()
+	histogram := CreateExponentialHistogramFromDataPoint(dp, maxBuckets)
+	// A bucket that decreased cannot be subtracted from, so it is handled like a reset
+	var delta *ExponentialHistogram
+	increased := false
+	if state.exponential != nil {
+		delta, increased = histogram.Subtract(state.exponential)
+	}
+	reset := !state.seen || dp.StartTimestamp() != state.startTS || count < state.count || !increased
+
+	state.seen = true
+	state.startTS = dp.StartTimestamp()
+	if !reset {
+		delta.CopyToDataPoint(dp)
+		dp.SetCount(count - state.count)
+		dp.SetSum(sum - state.sum)
+		dp.RemoveMin()
+		dp.RemoveMax()
+		dp.SetStartTimestamp(state.lastTS)
+	}
+	state.count = count
+	state.sum = sum
+	state.exponential = histogram
+	state.lastTS = dp.Timestamp()
+}
+
+// DeltaToCumulativeExponentialHistogram replaces the delta buckets by the running totals since the series was first seen,
+// at a lower scale when the totals would not fit in the maximum number of buckets
+func DeltaToCumulativeExponentialHistogram(state *SeriesState, dp pmetric.ExponentialHistogramDataPoint, maxBuckets int) {
+	if !state.seen {
+		state.seen = true
+		state.startTS = dp.StartTimestamp()
+		state.count = 0
+		state.sum = 0
+		state.MinMax = MinMax{}
+		state.exponential = CreateExponentialHistogram(maxBuckets)
+	}
+
+	state.exponential.Merge(CreateExponentialHistogramFromDataPoint(dp, maxBuckets))
+	state.count += dp.Count()
+	state.sum += dp.Sum()
+	state.MergeMinMax(DataPointMinMax(dp))
+
+	state.exponential.CopyToDataPoint(dp)
+	dp.SetCount(state.count)
+	dp.SetSum(state.sum)
+	if state.hasMin {
+		dp.SetMin(state.min)
+	}
+	if state.hasMax {
+		dp.SetMax(state.max)
+	}
+	dp.SetStartTimestamp(state.startTS)
+	state.lastTS = dp.Timestamp()
+}